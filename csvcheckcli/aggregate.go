@@ -0,0 +1,324 @@
+package csvcheckcli
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+)
+
+// The functions the aggregate function can compute for each group.
+const (
+	AggregateSum   = "sum"
+	AggregateCount = "count"
+	AggregateMin   = "min"
+	AggregateMax   = "max"
+	AggregateAvg   = "avg"
+)
+
+// The number of significant digits aggregates are rounded to, so that sums of
+// decimals do not show floating point noise.
+const aggregatePrecision = 12
+
+// For holding an aggregate computed for each group of rows.
+type Aggregate struct {
+	Function string
+	Column   string // Empty for counting the rows of the group.
+}
+
+// Returns the name of the column holding the aggregate, such as sum_amount, or
+// count for counting the rows.
+func (a Aggregate) Name() string {
+	if a.Column == "" {
+		return a.Function
+	}
+	return a.Function + "_" + a.Column
+}
+
+// Parses aggregates of the form function:column, such as sum:amount. count may be
+// given without a column to count the rows of each group, and with one to count
+// the non-empty values of the column.
+func ParseAggregates(specs []string) ([]Aggregate, error) {
+	res := make([]Aggregate, 0, len(specs))
+	names := make(map[string]bool)
+	for _, spec := range specs {
+		function, column, _ := strings.Cut(spec, ":")
+		aggregate := Aggregate{Function: strings.ToLower(function), Column: column}
+		switch aggregate.Function {
+		case AggregateCount:
+		case AggregateSum, AggregateMin, AggregateMax, AggregateAvg:
+			if column == "" {
+				return nil, fmt.Errorf("aggregate %s needs a column, such as %s:amount", spec, aggregate.Function)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported aggregate %s, expected %s, %s, %s, %s or %s", spec, AggregateSum, AggregateCount, AggregateMin, AggregateMax, AggregateAvg)
+		}
+		if names[aggregate.Name()] {
+			return nil, fmt.Errorf("aggregate %s is given more than once", spec)
+		}
+		names[aggregate.Name()] = true
+		res = append(res, aggregate)
+	}
+	return res, nil
+}
+
+// For holding the aggregates of the rows of a csv array with the same key.
+type aggregateGroup struct {
+	key       string
+	keyValues []csvcheck.StringHashable
+	firstRow  int              // The index of the first row of the group in the csv array.
+	values    []float64        // The aggregates, in the order they are given.
+	sums      []compensatedSum // The running sums of the sum and avg aggregates.
+	present   []bool           // Whether each aggregate has a value, which it does not if the column only has empty values.
+	counts    []int            // The number of non-empty values of each aggregate column.
+}
+
+// For holding a running sum that keeps the low-order parts lost to rounding, using
+// Neumaier summation, so that the sum does not depend on the order of the values.
+type compensatedSum struct {
+	sum          float64
+	compensation float64 // The low-order parts lost from the sum, added back by value.
+}
+
+// Adds the number to the sum.
+func (s *compensatedSum) add(number float64) {
+	sum := s.sum + number
+	if math.Abs(s.sum) >= math.Abs(number) {
+		s.compensation += (s.sum - sum) + number
+	} else {
+		s.compensation += (number - sum) + s.sum
+	}
+	s.sum = sum
+}
+
+// Returns the sum with the lost low-order parts added back.
+func (s compensatedSum) value() float64 {
+	return s.sum + s.compensation
+}
+
+// Returns the groups of the rows of the csv array with the same values in the key
+// columns, in the order their first rows appear in, and the index of each group by key.
+func aggregateCsvArray(csvArray [][]csvcheck.StringHashable, keyIndices []int, aggregates []Aggregate, columnIndices []int, file string) ([]*aggregateGroup, map[string]int, error) {
+	groups := []*aggregateGroup{}
+	positions := make(map[string]int)
+	for i := 1; i < len(csvArray); i++ {
+		row := csvArray[i]
+		key := getKeyString(row, keyIndices)
+		position, exists := positions[key]
+		if !exists {
+			keyValues := make([]csvcheck.StringHashable, len(keyIndices))
+			for k, index := range keyIndices {
+				keyValues[k] = row[index]
+			}
+			position = len(groups)
+			positions[key] = position
+			group := &aggregateGroup{
+				key:       key,
+				keyValues: keyValues,
+				firstRow:  i,
+				values:    make([]float64, len(aggregates)),
+				sums:      make([]compensatedSum, len(aggregates)),
+				present:   make([]bool, len(aggregates)),
+				counts:    make([]int, len(aggregates)),
+			}
+			// Counts are 0 rather than missing for groups without values.
+			for a, aggregate := range aggregates {
+				group.present[a] = aggregate.Function == AggregateCount
+			}
+			groups = append(groups, group)
+		}
+		group := groups[position]
+
+		for a, aggregate := range aggregates {
+			if aggregate.Column == "" {
+				group.values[a]++
+				continue
+			}
+			value := row[columnIndices[a]].StringHash()
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+			group.counts[a]++
+			if aggregate.Function == AggregateCount {
+				group.values[a]++
+				continue
+			}
+
+			number, isNumber := parseNumber(value)
+			if !isNumber {
+				return nil, nil, fmt.Errorf("value %q of column %s in row %d of the %s csv is not a number", value, aggregate.Column, i, file)
+			}
+			switch {
+			case aggregate.Function == AggregateSum || aggregate.Function == AggregateAvg:
+				group.sums[a].add(number)
+			case !group.present[a]:
+				group.values[a] = number
+			case aggregate.Function == AggregateMin:
+				group.values[a] = min(group.values[a], number)
+			case aggregate.Function == AggregateMax:
+				group.values[a] = max(group.values[a], number)
+			}
+			group.present[a] = true
+		}
+	}
+
+	for _, group := range groups {
+		for a, aggregate := range aggregates {
+			if !group.present[a] {
+				continue
+			}
+			switch aggregate.Function {
+			case AggregateSum:
+				group.values[a] = group.sums[a].value()
+			case AggregateAvg:
+				group.values[a] = group.sums[a].value() / float64(group.counts[a])
+			}
+		}
+	}
+	return groups, positions, nil
+}
+
+// Returns true iff the aggregates of the groups differ by more than the tolerance.
+// The aggregates are compared as they are shown, so that floating point noise does
+// not make otherwise equal sums differ.
+func aggregateGroupsDiffer(group1, group2 *aggregateGroup, tolerance float64) bool {
+	for a := range group1.values {
+		if group1.present[a] != group2.present[a] {
+			return true
+		}
+		if group1.present[a] && math.Abs(roundAggregate(group1.values[a])-roundAggregate(group2.values[a])) > tolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the aggregate rounded to aggregatePrecision significant digits.
+func roundAggregate(v float64) float64 {
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'g', aggregatePrecision, 64), 64)
+	return rounded
+}
+
+// Returns the aggregate rounded and formatted without an exponent.
+func formatAggregate(v float64) string {
+	return strconv.FormatFloat(roundAggregate(v), 'f', -1, 64)
+}
+
+// Returns the result array of the groups with the key columns and aggregates, and
+// the indices of the first rows of the groups.
+func getAggregateResArray(groups []*aggregateGroup, keyColumns []csvcheck.StringHashable, aggregates []Aggregate) ([][]csvcheck.StringHashable, []int) {
+	header := append([]csvcheck.StringHashable{}, keyColumns...)
+	for _, aggregate := range aggregates {
+		header = append(header, csvcheck.BasicStringHashable(aggregate.Name()))
+	}
+
+	res := [][]csvcheck.StringHashable{header}
+	indices := []int{0}
+	for _, group := range groups {
+		row := append([]csvcheck.StringHashable{}, group.keyValues...)
+		for a := range aggregates {
+			value := ""
+			if group.present[a] {
+				value = formatAggregate(group.values[a])
+			}
+			row = append(row, csvcheck.BasicStringHashable(value))
+		}
+		res = append(res, row)
+		indices = append(indices, group.firstRow)
+	}
+	return res, indices
+}
+
+// Returns the result arrays of the aggregate function. The rows of each csv array
+// are grouped by the key columns and the aggregates of the config are computed for
+// each group. The groups whose aggregates differ by more than the tolerance, or
+// that are missing from the other csv array, are returned with their key columns
+// and aggregates. The indices are those of the first rows of the groups.
+func getAggregateResArrays(csvArray1, csvArray2 [][]csvcheck.StringHashable, cfg Config) ([][]csvcheck.StringHashable, [][]csvcheck.StringHashable, []int, []int, error) {
+	header1, header2 := csvArray1[0], csvArray2[0]
+	aggregates, err := ParseAggregates(cfg.Aggregates)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	keyColumns, err := resolveColumns("keycolumns", cfg.KeyColumns, cfg.NormalizeHeaders, header1, header2)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	aggregateColumns := []csvcheck.StringHashable{}
+	for a, aggregate := range aggregates {
+		if aggregate.Column == "" {
+			continue
+		}
+		columns, err := resolveColumns("aggregates", []string{aggregate.Column}, cfg.NormalizeHeaders, header1, header2)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		aggregates[a].Column = columns[0].StringHash()
+		aggregateColumns = append(aggregateColumns, columns[0])
+	}
+	for _, header := range [][]csvcheck.StringHashable{header1, header2} {
+		err = checkColumnsExist("keycolumns", keyColumns, header)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		err = checkColumnsExist("aggregates", aggregateColumns, header)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+	}
+	isKey := make(map[string]bool)
+	for _, column := range keyColumns {
+		isKey[column.StringHash()] = true
+	}
+	for _, aggregate := range aggregates {
+		if isKey[aggregate.Name()] {
+			return nil, nil, nil, nil, fmt.Errorf("aggregate %s has the same name as a key column", aggregate.Name())
+		}
+	}
+
+	groupsOf := func(csvArray [][]csvcheck.StringHashable, file string) ([]*aggregateGroup, map[string]int, error) {
+		header := csvArray[0]
+		for i, row := range csvArray[1:] {
+			if len(row) != len(header) {
+				return nil, nil, fmt.Errorf("row %d of the %s csv has %d columns but the header has %d", i+1, file, len(row), len(header))
+			}
+		}
+		columnIndices := make([]int, len(aggregates))
+		for a, aggregate := range aggregates {
+			if aggregate.Column != "" {
+				columnIndices[a] = getColumnIndex(header, aggregate.Column)
+			}
+		}
+		return aggregateCsvArray(csvArray, getColumnIndices(header, keyColumns), aggregates, columnIndices, file)
+	}
+	groups1, positions1, err := groupsOf(csvArray1, "first")
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	groups2, positions2, err := groupsOf(csvArray2, "second")
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	differing1 := []*aggregateGroup{}
+	for _, group := range groups1 {
+		position, exists := positions2[group.key]
+		if !exists || aggregateGroupsDiffer(group, groups2[position], cfg.Tolerance) {
+			differing1 = append(differing1, group)
+		}
+	}
+	differing2 := []*aggregateGroup{}
+	for _, group := range groups2 {
+		position, exists := positions1[group.key]
+		if !exists || aggregateGroupsDiffer(groups1[position], group, cfg.Tolerance) {
+			differing2 = append(differing2, group)
+		}
+	}
+
+	res1, indices1 := getAggregateResArray(differing1, keyColumns, aggregates)
+	res2, indices2 := getAggregateResArray(differing2, keyColumns, aggregates)
+	return res1, res2, indices1, indices2, nil
+}
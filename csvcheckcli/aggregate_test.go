@@ -0,0 +1,143 @@
+package csvcheckcli_test
+
+import (
+	"context"
+	"csvcheckcli/csvcheckcli"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAggregates(t *testing.T) {
+	for i, data := range []struct {
+		specs       []string
+		expected    []csvcheckcli.Aggregate
+		expectError bool
+	}{
+		{
+			specs:    []string{"sum:amount", "COUNT", "count:note", "avg:a:b"},
+			expected: []csvcheckcli.Aggregate{{Function: "sum", Column: "amount"}, {Function: "count"}, {Function: "count", Column: "note"}, {Function: "avg", Column: "a:b"}},
+		},
+		{specs: []string{"sum"}, expectError: true},
+		{specs: []string{"median:amount"}, expectError: true},
+		{specs: []string{"max:amount", "max:amount"}, expectError: true},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		aggregates, err := csvcheckcli.ParseAggregates(data.specs)
+		if data.expectError {
+			assert.NotNil(t, err, indexString)
+			continue
+		}
+		assert.Nil(t, err, indexString)
+		assert.Equal(t, data.expected, aggregates, indexString)
+	}
+}
+
+func TestCompareAggregate(t *testing.T) {
+	csvString1 := `
+account,date,amount,note
+a,2024-01-01,10.10,x
+a,2024-01-01,20.20,
+b,2024-01-01,5,y
+a,2024-01-02,1,
+c,2024-01-02,,z
+`
+	csvString2 := `
+date,account,amount,note
+2024-01-01,a,30.3,
+2024-01-01,b,4.995,y
+2024-01-01,b,0.01,
+2024-01-02,a,2,
+2024-01-03,d,7,
+`
+
+	for i, data := range []struct {
+		aggregates  []string
+		tolerance   float64
+		keepIndex   bool
+		expected1   string
+		expected2   string
+		expectError bool
+	}{
+		{
+			aggregates: []string{"sum:amount"},
+			expected1:  "account,date,sum_amount\nb,2024-01-01,5\na,2024-01-02,1\nc,2024-01-02,\n",
+			expected2:  "account,date,sum_amount\nb,2024-01-01,5.005\na,2024-01-02,2\nd,2024-01-03,7\n",
+		},
+		{
+			aggregates: []string{"sum:amount"},
+			tolerance:  0.01,
+			keepIndex:  true,
+			expected1:  "account,date,sum_amount,_ind\na,2024-01-02,1,4\nc,2024-01-02,,5\n",
+			expected2:  "account,date,sum_amount,_ind\na,2024-01-02,2,4\nd,2024-01-03,7,5\n",
+		},
+		{
+			aggregates: []string{"count", "count:note", "min:amount", "max:amount", "avg:amount"},
+			tolerance:  10,
+			expected1:  "account,date,count,count_note,min_amount,max_amount,avg_amount\na,2024-01-01,2,1,10.1,20.2,15.15\nc,2024-01-02,1,1,,,\n",
+			expected2:  "account,date,count,count_note,min_amount,max_amount,avg_amount\na,2024-01-01,1,0,30.3,30.3,30.3\nd,2024-01-03,1,0,7,7,7\n",
+		},
+		{
+			aggregates:  []string{"sum:note"},
+			expectError: true,
+		},
+		{
+			aggregates:  []string{"sum:total"},
+			expectError: true,
+		},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		cfg := csvcheckcli.NewConfig(
+			csvcheckcli.WithFunction(csvcheckcli.FunctionStringAggregate),
+			csvcheckcli.WithKeyColumns("account", "date"),
+			csvcheckcli.WithKeepIndex(data.keepIndex),
+		)
+		cfg.Aggregates = data.aggregates
+		cfg.Tolerance = data.tolerance
+
+		result, err := csvcheckcli.Compare(context.Background(), strings.NewReader(csvString1), strings.NewReader(csvString2), cfg)
+
+		if data.expectError {
+			assert.NotNil(t, err, indexString)
+			continue
+		}
+		assert.Nil(t, err, indexString)
+		assert.Equal(t, Get2DArrayFromCsvString(data.expected1), result.Rows1, indexString)
+		assert.Equal(t, Get2DArrayFromCsvString(data.expected2), result.Rows2, indexString)
+	}
+}
+
+func TestCompareAggregateSumDoesNotDependOnRowOrder(t *testing.T) {
+	cfg := csvcheckcli.NewConfig(
+		csvcheckcli.WithFunction(csvcheckcli.FunctionStringAggregate),
+		csvcheckcli.WithKeyColumns("account"),
+		csvcheckcli.WithAggregates("sum:amount", "avg:amount"),
+	)
+	result, err := csvcheckcli.Compare(
+		context.Background(),
+		strings.NewReader("account,amount\na,1e16\na,1\na,-1e16\n"),
+		strings.NewReader("account,amount\na,1e16\na,-1e16\na,1\n"),
+		cfg,
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, Get2DArrayFromCsvString("account,sum_amount,avg_amount\n"), result.Rows1)
+	assert.Equal(t, Get2DArrayFromCsvString("account,sum_amount,avg_amount\n"), result.Rows2)
+}
+
+func TestCompareAggregateSortBy(t *testing.T) {
+	cfg := csvcheckcli.NewConfig(
+		csvcheckcli.WithFunction(csvcheckcli.FunctionStringAggregate),
+		csvcheckcli.WithKeyColumns("account"),
+	)
+	cfg.Aggregates = []string{"sum:amount"}
+	cfg.SortBy = []string{"sum_amount:desc"}
+
+	result, err := csvcheckcli.Compare(context.Background(), strings.NewReader("account,amount\na,1\nb,30\nc,2\n"), strings.NewReader("account,amount\na,2\n"), cfg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, Get2DArrayFromCsvString("account,sum_amount\nb,30\nc,2\na,1\n"), result.Rows1)
+	assert.Equal(t, Get2DArrayFromCsvString("account,sum_amount\na,2\n"), result.Rows2)
+}
@@ -0,0 +1,331 @@
+package csvcheckcli
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+)
+
+// The byte order mark some editors prepend to the first header.
+const byteOrderMark = "\uFEFF"
+
+// The maximum number of near-miss candidates listed in an error.
+const maxNearMissCandidates = 3
+
+// The prefix of the column names generated for csv files without a header row.
+const GeneratedColumnPrefix = "col"
+
+// The prefix marking a positional column reference such as #3.
+const PositionalColumnPrefix = "#"
+
+// The prefix marking a column pattern as a regular expression, such as re:^tmp_\d+$.
+const RegexColumnPrefix = "re:"
+
+// The characters marking a column pattern as a glob, such as audit_*.
+const globColumnCharacters = "*?["
+
+// Returns true iff r separates the words of a header.
+func isHeaderSeparator(r rune) bool {
+	return r == ' ' || r == '\t' || r == '_' || r == '-' || r == '.'
+}
+
+// Returns the header normalized for matching purposes. The byte order mark is
+// stripped, surrounding whitespace is trimmed, letters are lowercased and runs of
+// spaces, tabs, underscores, hyphens and dots are collapsed into a single underscore.
+func NormalizeHeader(header string) string {
+	header = strings.TrimPrefix(header, byteOrderMark)
+	header = strings.ToLower(strings.TrimSpace(header))
+
+	var builder strings.Builder
+	inSeparator := false
+	for _, r := range header {
+		if isHeaderSeparator(r) {
+			if !inSeparator {
+				builder.WriteByte('_')
+			}
+			inSeparator = true
+			continue
+		}
+		inSeparator = false
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// Returns a shallow copy of the csv array with the header row normalized.
+func normalizeHeaderRow(arr [][]csvcheck.StringHashable) [][]csvcheck.StringHashable {
+	if len(arr) == 0 {
+		return arr
+	}
+
+	res := make([][]csvcheck.StringHashable, len(arr))
+	copy(res, arr)
+	res[0] = make([]csvcheck.StringHashable, len(arr[0]))
+	for i, column := range arr[0] {
+		res[0][i] = csvcheck.BasicStringHashable(NormalizeHeader(column.StringHash()))
+	}
+	return res
+}
+
+// Returns a new csv array with a generated header row (col1, col2, ...) placed
+// above the given rows.
+func addGeneratedHeaderRow(arr [][]csvcheck.StringHashable) [][]csvcheck.StringHashable {
+	length := 0
+	if len(arr) > 0 {
+		length = len(arr[0])
+	}
+
+	header := make([]csvcheck.StringHashable, length)
+	for i := range header {
+		header[i] = csvcheck.BasicStringHashable(fmt.Sprintf("%s%d", GeneratedColumnPrefix, i+1))
+	}
+	return append([][]csvcheck.StringHashable{header}, arr...)
+}
+
+// Returns the 1-based position of a positional column reference such as #3 and
+// whether the column is one.
+func parseColumnPosition(column string) (int, bool) {
+	if !strings.HasPrefix(column, PositionalColumnPrefix) {
+		return 0, false
+	}
+	position, err := strconv.Atoi(column[len(PositionalColumnPrefix):])
+	if err != nil {
+		return 0, false
+	}
+	return position, true
+}
+
+// Returns a StringHashable row from the given column names. Positional references
+// are replaced by the column at that position in the headers, column patterns are
+// kept as they are for expandColumnPatterns and the remaining names are normalized
+// first if normalize is true. An error is returned if a positional reference names
+// different columns in the headers, as the column would not be in every csv.
+func resolveColumns(flagName string, columns []string, normalize bool, headers ...[]csvcheck.StringHashable) ([]csvcheck.StringHashable, error) {
+	if columns == nil {
+		return nil, nil
+	}
+
+	res := []csvcheck.StringHashable{}
+	for _, column := range columns {
+		position, isPositional := parseColumnPosition(column)
+		if isColumnPattern(column) {
+			res = append(res, csvcheck.BasicStringHashable(column))
+			continue
+		}
+		if !isPositional {
+			if normalize {
+				column = NormalizeHeader(column)
+			}
+			res = append(res, csvcheck.BasicStringHashable(column))
+			continue
+		}
+
+		resolved := ""
+		found := false
+		for _, header := range headers {
+			if position < 1 || position > len(header) {
+				continue
+			}
+			name := header[position-1].StringHash()
+			if found && name != resolved {
+				return nil, fmt.Errorf("column position %s given in %s names different columns %q and %q in the csv files, give the column by name instead", column, flagName, resolved, name)
+			}
+			resolved = name
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("column position %s given in %s is out of range", column, flagName)
+		}
+		res = append(res, csvcheck.BasicStringHashable(resolved))
+	}
+	return res, nil
+}
+
+// Returns true iff the column is a regular expression or glob pattern.
+func isColumnPattern(column string) bool {
+	return strings.HasPrefix(column, RegexColumnPrefix) || strings.ContainsAny(column, globColumnCharacters)
+}
+
+// Returns a function reporting whether a column name matches the column pattern.
+func compileColumnPattern(flagName, pattern string) (func(string) bool, error) {
+	if strings.HasPrefix(pattern, RegexColumnPrefix) {
+		regex, err := regexp.Compile(pattern[len(RegexColumnPrefix):])
+		if err != nil {
+			return nil, fmt.Errorf("invalid column pattern %q given in %s: %w", pattern, flagName, err)
+		}
+		return regex.MatchString, nil
+	}
+
+	_, err := path.Match(pattern, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid column pattern %q given in %s: %w", pattern, flagName, err)
+	}
+	return func(name string) bool {
+		matched, _ := path.Match(pattern, name)
+		return matched
+	}, nil
+}
+
+// Returns the columns with each column pattern replaced by the columns of the headers
+// it matches, in the order of the headers. Columns matched by several patterns are only
+// added once. A pattern that is also the exact name of a column is taken as that name.
+// An error is returned if a pattern matches no column of any of the headers.
+func expandColumnPatterns(flagName string, columns []csvcheck.StringHashable, headers ...[]csvcheck.StringHashable) ([]csvcheck.StringHashable, error) {
+	if columns == nil {
+		return nil, nil
+	}
+
+	names := make(map[string]bool)
+	for _, header := range headers {
+		for _, column := range header {
+			names[column.StringHash()] = true
+		}
+	}
+
+	res := []csvcheck.StringHashable{}
+	added := make(map[string]bool)
+	for _, column := range columns {
+		pattern := column.StringHash()
+		if !isColumnPattern(pattern) || names[pattern] {
+			res = append(res, column)
+			continue
+		}
+
+		matches, err := compileColumnPattern(flagName, pattern)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, header := range headers {
+			for _, v := range header {
+				name := v.StringHash()
+				if !matches(name) {
+					continue
+				}
+				found = true
+				if !added[name] {
+					added[name] = true
+					res = append(res, v)
+				}
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("column pattern %q given in %s matches no columns", pattern, flagName)
+		}
+	}
+	return res, nil
+}
+
+// Returns the edit distance between the two strings.
+func levenshteinDistance(s1, s2 string) int {
+	r1 := []rune(s1)
+	r2 := []rune(s2)
+
+	previous := make([]int, len(r2)+1)
+	current := make([]int, len(r2)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(r1); i++ {
+		current[0] = i
+		for j := 1; j <= len(r2); j++ {
+			cost := 1
+			if r1[i-1] == r2[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(r2)]
+}
+
+// Returns a distance between the two headers after normalization and whether
+// they are similar enough to be considered a near miss of each other.
+func headerSimilarity(header1, header2 string) (int, bool) {
+	normalized1 := NormalizeHeader(header1)
+	normalized2 := NormalizeHeader(header2)
+	if normalized1 == "" || normalized2 == "" {
+		return 0, false
+	}
+	if normalized1 == normalized2 {
+		return 0, true
+	}
+
+	distance := levenshteinDistance(normalized1, normalized2)
+	threshold := max(1, min(len(normalized1), len(normalized2))/3)
+	if distance <= threshold {
+		return distance, true
+	}
+	if strings.Contains(normalized1, normalized2) || strings.Contains(normalized2, normalized1) {
+		return distance, true
+	}
+	return distance, false
+}
+
+// Returns the columns of the headers that closely resemble column, closest first.
+func nearMissColumns(column string, headers ...[]csvcheck.StringHashable) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+
+	seen := make(map[string]bool)
+	candidates := []candidate{}
+	for _, header := range headers {
+		for _, v := range header {
+			name := v.StringHash()
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if distance, similar := headerSimilarity(column, name); similar {
+				candidates = append(candidates, candidate{name, distance})
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	res := []string{}
+	for i := 0; i < len(candidates) && i < maxNearMissCandidates; i++ {
+		res = append(res, candidates[i].name)
+	}
+	return res
+}
+
+// Returns an error if any of the columns exists in none of the headers. The
+// error lists the closest matching columns as candidates.
+func checkColumnsExist(flagName string, columns []csvcheck.StringHashable, headers ...[]csvcheck.StringHashable) error {
+	marker := make(map[string]bool)
+	for _, header := range headers {
+		for _, column := range header {
+			marker[column.StringHash()] = true
+		}
+	}
+
+	for _, column := range columns {
+		s := column.StringHash()
+		if marker[s] {
+			continue
+		}
+
+		candidates := nearMissColumns(s, headers...)
+		if len(candidates) == 0 {
+			return fmt.Errorf("column %q given in %s not found", s, flagName)
+		}
+		quoted := make([]string, len(candidates))
+		for i, candidate := range candidates {
+			quoted[i] = fmt.Sprintf("%q", candidate)
+		}
+		return fmt.Errorf("column %q given in %s not found, did you mean %s?", s, flagName, strings.Join(quoted, ", "))
+	}
+	return nil
+}
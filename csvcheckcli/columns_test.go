@@ -0,0 +1,246 @@
+package csvcheckcli_test
+
+import (
+	"csvcheckcli/csvcheckcli"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeHeader(t *testing.T) {
+	for i, data := range []struct {
+		header   string
+		expected string
+	}{
+		{header: "Customer ID", expected: "customer_id"},
+		{header: "customer_id ", expected: "customer_id"},
+		{header: "\uFEFFCustomer-ID", expected: "customer_id"},
+		{header: "  customer .. id\t", expected: "customer_id"},
+		{header: "_ind", expected: "_ind"},
+		{header: "", expected: ""},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		assert.Equal(t, data.expected, csvcheckcli.NormalizeHeader(data.header), indexString)
+	}
+}
+
+func TestGetResArraysDifferentMatchNormalizeHeaders(t *testing.T) {
+	input := userInputSolid{
+		inputDir:            "/path/to/input/dir",
+		files:               []string{"file1.csv", "file2.csv"},
+		method:              csvcheckcli.MethodStringMatch,
+		function:            csvcheckcli.FunctionStringDifferent,
+		keepIndex:           true,
+		columnsToUse:        []string{"Customer ID"},
+		autoAlign:           true,
+		ColumnsToKeep:       []string{"customer id", "AMOUNT", "_ind"},
+		ColumnsArrangement1: []string{"_ind", "Customer-ID", "amount"},
+		ColumnsArrangement2: []string{"_ind", "customer_id", "amount"},
+		normalizeHeaders:    true,
+	}.getUserInput()
+
+	arr1 := Get2DArrayFromCsvString("\uFEFFCustomer ID,Amount,Note\n1,10,x\n2,20,y\n")
+	arr2 := Get2DArrayFromCsvString(`
+amount ,customer_id
+10,1
+30,3
+`)
+	res1, res2, err := csvcheckcli.GetResArrays(arr1, arr2, input)
+
+	expected1 := Get2DArrayFromCsvString(fmt.Sprintf(`
+%s,customer_id,amount
+2,2,20
+`, csvcheckcli.IndexColumnName))
+
+	expected2 := Get2DArrayFromCsvString(fmt.Sprintf(`
+%s,customer_id,amount
+2,3,30
+`, csvcheckcli.IndexColumnName))
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected1, res1)
+	assert.Equal(t, expected2, res2)
+}
+
+func TestGetResArraysNearMissColumnError(t *testing.T) {
+	input := userInputSolid{
+		inputDir:     "/path/to/input/dir",
+		files:        []string{"file1.csv", "file2.csv"},
+		method:       csvcheckcli.MethodStringMatch,
+		function:     csvcheckcli.FunctionStringDifferent,
+		columnsToUse: []string{"Customer ID"},
+	}.getUserInput()
+
+	arr1 := Get2DArrayFromCsvString(`
+customer_id,amount
+1,10
+`)
+	arr2 := Get2DArrayFromCsvString(`
+customer_id,amount
+1,10
+`)
+	_, _, err := csvcheckcli.GetResArrays(arr1, arr2, input)
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), `"Customer ID"`)
+	assert.Contains(t, err.Error(), `did you mean "customer_id"`)
+}
+
+func TestGetResArraysDifferentMatchNoHeaderPositionalColumns(t *testing.T) {
+	input := userInputSolid{
+		inputDir:            "/path/to/input/dir",
+		files:               []string{"file1.csv", "file2.csv"},
+		method:              csvcheckcli.MethodStringMatch,
+		function:            csvcheckcli.FunctionStringDifferent,
+		keepIndex:           true,
+		columnsToUse:        []string{"#1", "#3"},
+		ColumnsToKeep:       []string{"#1", "#2", csvcheckcli.IndexColumnName},
+		ColumnsArrangement1: []string{csvcheckcli.IndexColumnName, "#2", "#1"},
+		ColumnsArrangement2: []string{csvcheckcli.IndexColumnName, "col1", "#2"},
+		noHeader1:           true,
+		noHeader2:           true,
+	}.getUserInput()
+
+	arr1 := Get2DArrayFromCsvString(`
+1,a,x
+2,b,y
+3,c,z
+`)
+	arr2 := Get2DArrayFromCsvString(`
+1,changed,x
+3,c,changed
+`)
+	res1, res2, err := csvcheckcli.GetResArrays(arr1, arr2, input)
+
+	expected1 := Get2DArrayFromCsvString(fmt.Sprintf(`
+%s,col2,col1
+2,b,2
+3,c,3
+`, csvcheckcli.IndexColumnName))
+
+	expected2 := Get2DArrayFromCsvString(fmt.Sprintf(`
+%s,col1,col2
+2,3,c
+`, csvcheckcli.IndexColumnName))
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected1, res1)
+	assert.Equal(t, expected2, res2)
+}
+
+func TestGetResArraysPositionalColumnOutOfRange(t *testing.T) {
+	input := userInputSolid{
+		inputDir:     "/path/to/input/dir",
+		files:        []string{"file1.csv", "file2.csv"},
+		method:       csvcheckcli.MethodStringMatch,
+		function:     csvcheckcli.FunctionStringDifferent,
+		columnsToUse: []string{"#4"},
+		noHeader1:    true,
+	}.getUserInput()
+
+	arr1 := Get2DArrayFromCsvString(`
+1,a,x
+`)
+	arr2 := Get2DArrayFromCsvString(`
+col1,col2,col3
+1,a,x
+`)
+	_, _, err := csvcheckcli.GetResArrays(arr1, arr2, input)
+
+	assert.NotNil(t, err)
+}
+
+func TestGetResArraysPositionalColumnNamesDifferentColumns(t *testing.T) {
+	input := userInputSolid{
+		inputDir:     "/path/to/input/dir",
+		files:        []string{"file1.csv", "file2.csv"},
+		method:       csvcheckcli.MethodStringMatch,
+		function:     csvcheckcli.FunctionStringDifferent,
+		columnsToUse: []string{"#2"},
+	}.getUserInput()
+
+	arr1 := Get2DArrayFromCsvString(`
+id,amount
+1,10
+`)
+	arr2 := Get2DArrayFromCsvString(`
+id,total
+1,10
+`)
+	_, _, err := csvcheckcli.GetResArrays(arr1, arr2, input)
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), `names different columns "amount" and "total"`)
+}
+
+func TestGetResArraysDifferentMatchColumnPatterns(t *testing.T) {
+	input := userInputSolid{
+		inputDir:        "/path/to/input/dir",
+		files:           []string{"file1.csv", "file2.csv"},
+		method:          csvcheckcli.MethodStringMatch,
+		function:        csvcheckcli.FunctionStringDifferent,
+		keepIndex:       true,
+		columnsToIgnore: []string{"audit_*", `re:^tmp_\d+$`},
+		ColumnsToDelete: []string{"re:^(audit|tmp)_", "_i?d"},
+	}.getUserInput()
+
+	arr1 := Get2DArrayFromCsvString(`
+id,amount,audit_user,audit_time,tmp_1
+1,10,a,t1,x
+2,20,b,t2,y
+`)
+	arr2 := Get2DArrayFromCsvString(`
+id,amount,audit_user,tmp_1,tmp_22
+1,10,c,z,z
+2,25,b,y,y
+`)
+	res1, res2, err := csvcheckcli.GetResArrays(arr1, arr2, input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, Get2DArrayFromCsvString("id,amount\n2,20\n"), res1)
+	assert.Equal(t, Get2DArrayFromCsvString("id,amount\n2,25\n"), res2)
+}
+
+func TestGetResArraysColumnPatternErrors(t *testing.T) {
+	arr1 := Get2DArrayFromCsvString(`
+id,price[usd],done?
+1,10,y
+`)
+	arr2 := Get2DArrayFromCsvString(`
+id,price[usd],done?
+1,10,n
+`)
+
+	for i, data := range []struct {
+		columnsToUse  []string
+		ColumnsToKeep []string
+		expectedError string
+	}{
+		{columnsToUse: []string{"audit_*"}, expectedError: `column pattern "audit_*" given in usecolumns matches no columns`},
+		{columnsToUse: []string{"re:^tmp_("}, expectedError: `invalid column pattern "re:^tmp_("`},
+		{columnsToUse: []string{"id"}, ColumnsToKeep: []string{"re:^note"}, expectedError: `column pattern "re:^note" given in keepcolumns matches no columns`},
+		// Patterns that are also the exact names of columns are taken as the names.
+		{columnsToUse: []string{"price[usd]", "done?"}},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		input := userInputSolid{
+			inputDir:      "/path/to/input/dir",
+			files:         []string{"file1.csv", "file2.csv"},
+			method:        csvcheckcli.MethodStringMatch,
+			function:      csvcheckcli.FunctionStringDifferent,
+			columnsToUse:  data.columnsToUse,
+			ColumnsToKeep: data.ColumnsToKeep,
+		}.getUserInput()
+
+		res1, _, err := csvcheckcli.GetResArrays(arr1, arr2, input)
+
+		if data.expectedError != "" {
+			assert.NotNil(t, err, indexString)
+			assert.Contains(t, err.Error(), data.expectedError, indexString)
+		} else {
+			assert.Nil(t, err, indexString)
+			assert.Len(t, res1, 2, indexString)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package csvcheckcli
+
+import (
+	"fmt"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+)
+
+// The name of the column holding the file each row of combined results comes from.
+const SourceColumnName = "_source"
+
+// Returns the result arrays as a single csv array with a SourceColumnName column in
+// front holding source1 or source2 for the rows of res1 or res2. The columns are
+// those of res1 followed by those only in res2, and columns missing from the results
+// of one of the files are left empty in its rows.
+func CombineResArrays(res1, res2 [][]csvcheck.StringHashable, source1, source2 string) ([][]csvcheck.StringHashable, error) {
+	if len(res1) == 0 || len(res2) == 0 {
+		return nil, fmt.Errorf("the result arrays must have a columns row")
+	}
+
+	columns := []csvcheck.StringHashable{csvcheck.BasicStringHashable(SourceColumnName)}
+	// The positions in columns of each occurrence of a column name, so that
+	// duplicate column names are lined up in the order they appear in.
+	positions := make(map[string][]int)
+	getPositions := func(header []csvcheck.StringHashable) ([]int, error) {
+		res := make([]int, len(header))
+		occurrences := make(map[string]int)
+		for i, column := range header {
+			name := column.StringHash()
+			if name == SourceColumnName {
+				return nil, fmt.Errorf("the results already have a %s column", SourceColumnName)
+			}
+			if occurrences[name] == len(positions[name]) {
+				positions[name] = append(positions[name], len(columns))
+				columns = append(columns, column)
+			}
+			res[i] = positions[name][occurrences[name]]
+			occurrences[name]++
+		}
+		return res, nil
+	}
+
+	positions1, err := getPositions(res1[0])
+	if err != nil {
+		return nil, err
+	}
+	positions2, err := getPositions(res2[0])
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([][]csvcheck.StringHashable, 0, len(res1)+len(res2)-1)
+	res = append(res, columns)
+	addRows := func(rows [][]csvcheck.StringHashable, rowPositions []int, source string) error {
+		for i, row := range rows {
+			if len(row) != len(rowPositions) {
+				return fmt.Errorf("row %d of the results of %s has %d columns but the header has %d", i+1, source, len(row), len(rowPositions))
+			}
+			combined := make([]csvcheck.StringHashable, len(columns))
+			combined[0] = csvcheck.BasicStringHashable(source)
+			for j := 1; j < len(combined); j++ {
+				combined[j] = csvcheck.BasicStringHashable("")
+			}
+			for j, value := range row {
+				combined[rowPositions[j]] = value
+			}
+			res = append(res, combined)
+		}
+		return nil
+	}
+
+	err = addRows(res1[1:], positions1, source1)
+	if err != nil {
+		return nil, err
+	}
+	err = addRows(res2[1:], positions2, source2)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,111 @@
+package csvcheckcli_test
+
+import (
+	"context"
+	"csvcheckcli/csvcheckcli"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCombineResArrays(t *testing.T) {
+	for i, data := range []struct {
+		resString1 string
+		resString2 string
+		expected   string
+	}{
+		{
+			resString1: `
+a,b,_ind
+1,2,1
+`,
+			resString2: `
+a,b,_ind
+3,4,2
+5,6,3
+`,
+			expected: `
+_source,a,b,_ind
+x.csv,1,2,1
+y.csv,3,4,2
+y.csv,5,6,3
+`,
+		},
+		{
+			resString1: `
+a,b,_ind
+1,2,1
+`,
+			resString2: `
+c,a,_ind
+3,4,2
+`,
+			expected: `
+_source,a,b,_ind,c
+x.csv,1,2,1,
+y.csv,4,,2,3
+`,
+		},
+		{
+			resString1: `
+a,a
+1,2
+`,
+			resString2: `
+a,b,a,a
+3,4,5,6
+`,
+			expected: `
+_source,a,a,b,a
+x.csv,1,2,,
+y.csv,3,5,4,6
+`,
+		},
+		{
+			resString1: `
+a
+`,
+			resString2: `
+b
+`,
+			expected: `
+_source,a,b
+`,
+		},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		res, err := csvcheckcli.CombineResArrays(Get2DArrayFromCsvString(data.resString1), Get2DArrayFromCsvString(data.resString2), "x.csv", "y.csv")
+		assert.Nil(t, err, indexString)
+		assert.Equal(t, Get2DArrayFromCsvString(data.expected), res, indexString)
+	}
+}
+
+func TestCombineResArraysSourceColumnExists(t *testing.T) {
+	res1 := Get2DArrayFromCsvString(fmt.Sprintf("a,%s\n1,2\n", csvcheckcli.SourceColumnName))
+	res2 := Get2DArrayFromCsvString("a\n1\n")
+
+	_, err := csvcheckcli.CombineResArrays(res1, res2, "x.csv", "y.csv")
+
+	assert.NotNil(t, err)
+}
+
+func TestCompareCombineKeepsIndex(t *testing.T) {
+	cfg := csvcheckcli.NewConfig(
+		csvcheckcli.WithMethod(csvcheckcli.MethodStringMatch),
+		csvcheckcli.WithFunction(csvcheckcli.FunctionStringDifferent),
+	)
+	cfg.Combine = true
+
+	result, err := csvcheckcli.Compare(context.Background(), strings.NewReader("a,b\n1,2\n3,4\n"), strings.NewReader("a,b\n1,2\n5,6\n"), cfg)
+	assert.Nil(t, err)
+
+	res, err := csvcheckcli.CombineResArrays(result.Rows1, result.Rows2, "x.csv", "y.csv")
+	assert.Nil(t, err)
+	assert.Equal(t, Get2DArrayFromCsvString(fmt.Sprintf(`
+%s,a,b,%s
+x.csv,3,4,2
+y.csv,5,6,2
+`, csvcheckcli.SourceColumnName, csvcheckcli.IndexColumnName)), res)
+}
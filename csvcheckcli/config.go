@@ -0,0 +1,730 @@
+package csvcheckcli
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// For holding the options of a comparison by value. Unlike UserInput, a Config
+// can be built directly in Go, with NewConfig and the With* options or as a literal.
+// Besides the options of the comparison, it holds those of the command line for
+// printing and writing the results, such as Limit, TUI and OutputDir, which Compare
+// ignores. Every field has a With* option.
+type Config struct {
+	InputDir              string
+	Files                 []string
+	Method                string
+	Function              string
+	KeepIndex             bool
+	OutputDir             string
+	AddTimestamp          bool
+	ColumnsToUse          []string
+	ColumnsToIgnore       []string
+	AutoAlign             bool
+	UseCommonColumns      bool
+	ColumnsToKeep         []string
+	ColumnsToDelete       []string
+	ColumnsArrangement1   []string
+	ColumnsArrangement2   []string
+	PrintInCsvFormat      bool
+	PrettyFormatMaxLength int
+	NormalizeHeaders      bool
+	NoHeader1             bool
+	NoHeader2             bool
+	Limit                 int
+	Head                  bool
+	Tail                  bool
+	Sample                int
+	Seed                  int64
+	Workers               int
+	Progress              bool
+	EmitHash              bool
+	KeyColumns            []string
+	PrintInJsonFormat     bool
+	PrintInMarkdownFormat bool
+	Overwrite             bool
+	NoClobber             bool
+	FileMode              os.FileMode
+	OutputName            string
+	Combine               bool
+	TUI                   bool
+	Watch                 bool
+	WatchInterval         time.Duration
+	Encoding1             string
+	Encoding2             string
+	OutputEncoding        string
+	UnicodeNormalize      string
+	StripAccents          bool
+	ArrangeMode           string
+	SortBy                []string
+	Aggregates            []string
+	Tolerance             float64
+
+	// Receives progress updates of the comparison if not nil. It has no flag
+	// and is not carried over to and from UserInput.
+	ProgressReporter ProgressReporter
+}
+
+// For setting an option of a Config in NewConfig.
+type Option func(*Config)
+
+// Returns a Config with the same defaults as the command-line flags
+// with the given options applied on top.
+func NewConfig(options ...Option) Config {
+	cfg := Config{
+		Method:                MethodStringSet,
+		PrettyFormatMaxLength: -1,
+		Workers:               runtime.NumCPU(),
+		FileMode:              DefaultFileMode,
+		WatchInterval:         DefaultWatchInterval,
+		ArrangeMode:           ArrangeModeStrict,
+	}
+	for _, option := range options {
+		option(&cfg)
+	}
+	return cfg
+}
+
+// Sets the method to use for comparison.
+func WithMethod(method string) Option {
+	return func(c *Config) { c.Method = method }
+}
+
+// Sets the function to use for comparison.
+func WithFunction(function string) Option {
+	return func(c *Config) { c.Function = function }
+}
+
+// Sets whether to add the indices of the rows in the original csv to the results.
+func WithKeepIndex(keepIndex bool) Option {
+	return func(c *Config) { c.KeepIndex = keepIndex }
+}
+
+// Sets the columns to use for comparison.
+func WithColumnsToUse(columns ...string) Option {
+	return func(c *Config) { c.ColumnsToUse = columns }
+}
+
+// Sets the columns to ignore for comparison.
+func WithColumnsToIgnore(columns ...string) Option {
+	return func(c *Config) { c.ColumnsToIgnore = columns }
+}
+
+// Sets whether to auto align the columns of the csv files.
+func WithAutoAlign(autoAlign bool) Option {
+	return func(c *Config) { c.AutoAlign = autoAlign }
+}
+
+// Sets whether to use all the common columns for comparison.
+func WithUseCommonColumns(useCommonColumns bool) Option {
+	return func(c *Config) { c.UseCommonColumns = useCommonColumns }
+}
+
+// Sets the columns to keep in the results.
+func WithColumnsToKeep(columns ...string) Option {
+	return func(c *Config) { c.ColumnsToKeep = columns }
+}
+
+// Sets the columns to delete in the results.
+func WithColumnsToDelete(columns ...string) Option {
+	return func(c *Config) { c.ColumnsToDelete = columns }
+}
+
+// Sets the arrangements of the columns in the first and second results.
+// A nil arrangement keeps the columns as they are.
+func WithColumnsArrangements(arrangement1, arrangement2 []string) Option {
+	return func(c *Config) {
+		c.ColumnsArrangement1 = arrangement1
+		c.ColumnsArrangement2 = arrangement2
+	}
+}
+
+// Sets whether to normalize the headers and given column names.
+func WithNormalizeHeaders(normalizeHeaders bool) Option {
+	return func(c *Config) { c.NormalizeHeaders = normalizeHeaders }
+}
+
+// Sets whether the first and second csv files have no header row.
+func WithNoHeader(noHeader1, noHeader2 bool) Option {
+	return func(c *Config) {
+		c.NoHeader1 = noHeader1
+		c.NoHeader2 = noHeader2
+	}
+}
+
+// Sets the number of goroutines used for hashing rows.
+func WithWorkers(workers int) Option {
+	return func(c *Config) { c.Workers = workers }
+}
+
+// Sets whether to add the fingerprints of the rows to the results.
+func WithEmitHash(emitHash bool) Option {
+	return func(c *Config) { c.EmitHash = emitHash }
+}
+
+// Sets the columns identifying a row, which rows are paired by.
+func WithKeyColumns(columns ...string) Option {
+	return func(c *Config) { c.KeyColumns = columns }
+}
+
+// Sets the receiver of progress updates of the comparison.
+func WithProgressReporter(reporter ProgressReporter) Option {
+	return func(c *Config) { c.ProgressReporter = reporter }
+}
+
+// Sets the encodings of the first and second csv files. An empty encoding reads the file as UTF-8 as it is.
+func WithEncodings(encoding1, encoding2 string) Option {
+	return func(c *Config) {
+		c.Encoding1 = encoding1
+		c.Encoding2 = encoding2
+	}
+}
+
+// Sets the unicode normalization form values are compared in.
+func WithUnicodeNormalize(form string) Option {
+	return func(c *Config) { c.UnicodeNormalize = form }
+}
+
+// Sets whether to compare values without their accents.
+func WithStripAccents(stripAccents bool) Option {
+	return func(c *Config) { c.StripAccents = stripAccents }
+}
+
+// Sets how columns missing from the arrangements are placed.
+func WithArrangeMode(mode string) Option {
+	return func(c *Config) { c.ArrangeMode = mode }
+}
+
+// Sets the columns the result rows are sorted by, such as amount:desc.
+func WithSortBy(keys ...string) Option {
+	return func(c *Config) { c.SortBy = keys }
+}
+
+// Sets the aggregates the aggregate function computes for each group, such as sum:amount.
+func WithAggregates(aggregates ...string) Option {
+	return func(c *Config) { c.Aggregates = aggregates }
+}
+
+// Sets the largest difference between aggregates or sums still taken as equal.
+func WithTolerance(tolerance float64) Option {
+	return func(c *Config) { c.Tolerance = tolerance }
+}
+
+// The options below only affect how the command line prints and writes the results.
+// Compare ignores them.
+
+// Sets the directory the input files are read from by the command line.
+func WithInputDir(inputDir string) Option {
+	return func(c *Config) { c.InputDir = inputDir }
+}
+
+// Sets the input files compared by the command line.
+func WithFiles(files ...string) Option {
+	return func(c *Config) { c.Files = files }
+}
+
+// Sets the directory the command line writes the results to.
+func WithOutputDir(outputDir string) Option {
+	return func(c *Config) { c.OutputDir = outputDir }
+}
+
+// Sets whether to add a timestamp to the names of the output files.
+func WithAddTimestamp(addTimestamp bool) Option {
+	return func(c *Config) { c.AddTimestamp = addTimestamp }
+}
+
+// Sets whether to print the results in csv format.
+func WithPrintInCsvFormat(printInCsvFormat bool) Option {
+	return func(c *Config) { c.PrintInCsvFormat = printInCsvFormat }
+}
+
+// Sets the maximum length of printed values before truncation. Negative values mean no limit.
+func WithPrettyFormatMaxLength(maxLength int) Option {
+	return func(c *Config) { c.PrettyFormatMaxLength = maxLength }
+}
+
+// Sets the maximum number of result rows printed for each file.
+func WithLimit(limit int) Option {
+	return func(c *Config) { c.Limit = limit }
+}
+
+// Sets whether to print the first rows when a limit is given.
+func WithHead(head bool) Option {
+	return func(c *Config) { c.Head = head }
+}
+
+// Sets whether to print the last rows when a limit is given.
+func WithTail(tail bool) Option {
+	return func(c *Config) { c.Tail = tail }
+}
+
+// Sets the number of randomly sampled result rows printed for each file.
+func WithSample(sample int) Option {
+	return func(c *Config) { c.Sample = sample }
+}
+
+// Sets the seed used for sampling.
+func WithSeed(seed int64) Option {
+	return func(c *Config) { c.Seed = seed }
+}
+
+// Sets whether the command line shows a progress bar.
+func WithProgress(progress bool) Option {
+	return func(c *Config) { c.Progress = progress }
+}
+
+// Sets whether to print the results in json format.
+func WithPrintInJsonFormat(printInJsonFormat bool) Option {
+	return func(c *Config) { c.PrintInJsonFormat = printInJsonFormat }
+}
+
+// Sets whether to print the results as markdown tables.
+func WithPrintInMarkdownFormat(printInMarkdownFormat bool) Option {
+	return func(c *Config) { c.PrintInMarkdownFormat = printInMarkdownFormat }
+}
+
+// Sets whether to replace output files that already exist.
+func WithOverwrite(overwrite bool) Option {
+	return func(c *Config) { c.Overwrite = overwrite }
+}
+
+// Sets whether to skip writing output files that already exist.
+func WithNoClobber(noClobber bool) Option {
+	return func(c *Config) { c.NoClobber = noClobber }
+}
+
+// Sets the permissions of the output files.
+func WithFileMode(fileMode os.FileMode) Option {
+	return func(c *Config) { c.FileMode = fileMode }
+}
+
+// Sets the template for the names of the output files.
+func WithOutputName(outputName string) Option {
+	return func(c *Config) { c.OutputName = outputName }
+}
+
+// Sets whether to print and write the results of both files as a single table.
+func WithCombine(combine bool) Option {
+	return func(c *Config) { c.Combine = combine }
+}
+
+// Sets whether to browse the results in the terminal.
+func WithTUI(tui bool) Option {
+	return func(c *Config) { c.TUI = tui }
+}
+
+// Sets whether the command line compares the files again whenever they change.
+func WithWatch(watch bool) Option {
+	return func(c *Config) { c.Watch = watch }
+}
+
+// Sets how often watched files are checked for changes.
+func WithWatchInterval(interval time.Duration) Option {
+	return func(c *Config) { c.WatchInterval = interval }
+}
+
+// Sets the encoding the output files are written in.
+func WithOutputEncoding(encoding string) Option {
+	return func(c *Config) { c.OutputEncoding = encoding }
+}
+
+// Returns the value p points to, or the zero value if p is nil.
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+// Returns the options of the user input by value. Nil fields are taken as zero values.
+func (u UserInput) Config() Config {
+	return Config{
+		InputDir:              deref(u.InputDir),
+		Files:                 deref(u.Files),
+		Method:                deref(u.Method),
+		Function:              deref(u.Function),
+		KeepIndex:             deref(u.KeepIndex),
+		OutputDir:             deref(u.OutputDir),
+		AddTimestamp:          deref(u.AddTimestamp),
+		ColumnsToUse:          deref(u.ColumnsToUse),
+		ColumnsToIgnore:       deref(u.ColumnsToIgnore),
+		AutoAlign:             deref(u.AutoAlign),
+		UseCommonColumns:      deref(u.UseCommonColumns),
+		ColumnsToKeep:         deref(u.ColumnsToKeep),
+		ColumnsToDelete:       deref(u.ColumnsToDelete),
+		ColumnsArrangement1:   deref(u.ColumnsArrangement1),
+		ColumnsArrangement2:   deref(u.ColumnsArrangement2),
+		PrintInCsvFormat:      deref(u.PrintInCsvFormat),
+		PrettyFormatMaxLength: deref(u.PrettyFormatMaxLength),
+		NormalizeHeaders:      deref(u.NormalizeHeaders),
+		NoHeader1:             deref(u.NoHeader1),
+		NoHeader2:             deref(u.NoHeader2),
+		Limit:                 deref(u.Limit),
+		Head:                  deref(u.Head),
+		Tail:                  deref(u.Tail),
+		Sample:                deref(u.Sample),
+		Seed:                  deref(u.Seed),
+		Workers:               deref(u.Workers),
+		Progress:              deref(u.Progress),
+		EmitHash:              deref(u.EmitHash),
+		KeyColumns:            deref(u.KeyColumns),
+		PrintInJsonFormat:     deref(u.PrintInJsonFormat),
+		PrintInMarkdownFormat: deref(u.PrintInMarkdownFormat),
+		Overwrite:             deref(u.Overwrite),
+		NoClobber:             deref(u.NoClobber),
+		FileMode:              deref(u.FileMode),
+		OutputName:            deref(u.OutputName),
+		Combine:               deref(u.Combine),
+		TUI:                   deref(u.TUI),
+		Watch:                 deref(u.Watch),
+		WatchInterval:         deref(u.WatchInterval),
+		Encoding1:             deref(u.Encoding1),
+		Encoding2:             deref(u.Encoding2),
+		OutputEncoding:        deref(u.OutputEncoding),
+		UnicodeNormalize:      deref(u.UnicodeNormalize),
+		StripAccents:          deref(u.StripAccents),
+		ArrangeMode:           deref(u.ArrangeMode),
+		SortBy:                deref(u.SortBy),
+		Aggregates:            deref(u.Aggregates),
+		Tolerance:             deref(u.Tolerance),
+	}
+}
+
+// Returns the options of the config as a UserInput pointing to a copy of them.
+func (c Config) UserInput() UserInput {
+	return UserInput{
+		InputDir:              &c.InputDir,
+		Files:                 &c.Files,
+		Method:                &c.Method,
+		Function:              &c.Function,
+		KeepIndex:             &c.KeepIndex,
+		OutputDir:             &c.OutputDir,
+		AddTimestamp:          &c.AddTimestamp,
+		ColumnsToUse:          &c.ColumnsToUse,
+		ColumnsToIgnore:       &c.ColumnsToIgnore,
+		AutoAlign:             &c.AutoAlign,
+		UseCommonColumns:      &c.UseCommonColumns,
+		ColumnsToKeep:         &c.ColumnsToKeep,
+		ColumnsToDelete:       &c.ColumnsToDelete,
+		ColumnsArrangement1:   &c.ColumnsArrangement1,
+		ColumnsArrangement2:   &c.ColumnsArrangement2,
+		PrintInCsvFormat:      &c.PrintInCsvFormat,
+		PrettyFormatMaxLength: &c.PrettyFormatMaxLength,
+		NormalizeHeaders:      &c.NormalizeHeaders,
+		NoHeader1:             &c.NoHeader1,
+		NoHeader2:             &c.NoHeader2,
+		Limit:                 &c.Limit,
+		Head:                  &c.Head,
+		Tail:                  &c.Tail,
+		Sample:                &c.Sample,
+		Seed:                  &c.Seed,
+		Workers:               &c.Workers,
+		Progress:              &c.Progress,
+		EmitHash:              &c.EmitHash,
+		KeyColumns:            &c.KeyColumns,
+		PrintInJsonFormat:     &c.PrintInJsonFormat,
+		PrintInMarkdownFormat: &c.PrintInMarkdownFormat,
+		Overwrite:             &c.Overwrite,
+		NoClobber:             &c.NoClobber,
+		FileMode:              &c.FileMode,
+		OutputName:            &c.OutputName,
+		Combine:               &c.Combine,
+		TUI:                   &c.TUI,
+		Watch:                 &c.Watch,
+		WatchInterval:         &c.WatchInterval,
+		Encoding1:             &c.Encoding1,
+		Encoding2:             &c.Encoding2,
+		OutputEncoding:        &c.OutputEncoding,
+		UnicodeNormalize:      &c.UnicodeNormalize,
+		StripAccents:          &c.StripAccents,
+		ArrangeMode:           &c.ArrangeMode,
+		SortBy:                &c.SortBy,
+		Aggregates:            &c.Aggregates,
+		Tolerance:             &c.Tolerance,
+	}
+}
+
+// Returns true iff the index column is added to the results.
+func (c Config) keepsIndex() bool {
+	return c.KeepIndex || c.Combine
+}
+
+// Returns how the output files are written.
+func (c Config) WriteOptions() WriteOptions {
+	return WriteOptions{Overwrite: c.Overwrite, FileMode: c.FileMode, Encoding: c.OutputEncoding}
+}
+
+// Checks that the input directory and exactly 2 files are given.
+func (c Config) validateFiles() error {
+	if c.InputDir == "" {
+		return fmt.Errorf("inputdir must be given")
+	}
+
+	if len(c.Files) != 2 {
+		return fmt.Errorf("exactly 2 file paths needed")
+	}
+
+	return nil
+}
+
+// Checks if the comparison options are valid.
+func (c Config) Validate() error {
+	if _, exists := MethodMappings[c.Method]; !exists && c.Method != MethodStringSorted {
+		return fmt.Errorf("unsupported method %s", c.Method)
+	}
+
+	columnsCompInputCnt := 0
+	if c.ColumnsToUse != nil {
+		columnsCompInputCnt++
+	}
+	if c.ColumnsToIgnore != nil {
+		columnsCompInputCnt++
+	}
+	if c.UseCommonColumns {
+		columnsCompInputCnt++
+	}
+	if columnsCompInputCnt > 1 {
+		return fmt.Errorf("usecolumns, ignorecolumns, and usecommoncolumns cannot be used together")
+	}
+
+	columnsResInputCnt := 0
+	if c.ColumnsToKeep != nil {
+		columnsResInputCnt++
+	}
+	if c.ColumnsToDelete != nil {
+		columnsResInputCnt++
+	}
+	if columnsResInputCnt > 1 {
+		return fmt.Errorf("keepcolumns and deletecolumns cannot be used together")
+	}
+
+	if c.Limit > 0 && c.Sample > 0 {
+		return fmt.Errorf("limit and sample cannot be used together")
+	}
+
+	if c.Head && c.Tail {
+		return fmt.Errorf("head and tail cannot be used together")
+	}
+
+	if (c.Head || c.Tail) && c.Limit <= 0 {
+		return fmt.Errorf("head and tail require a limit")
+	}
+
+	err := checkWriteFlags(c.Overwrite, c.NoClobber)
+	if err != nil {
+		return err
+	}
+
+	if c.OutputName != "" {
+		if c.AddTimestamp {
+			return fmt.Errorf("addtimestamp and outputname cannot be used together, use {date} in outputname instead")
+		}
+		err = CheckOutputName(c.OutputName)
+		if err != nil {
+			return err
+		}
+	}
+
+	printFormatCnt := 0
+	for _, printFormat := range []bool{c.PrintInCsvFormat, c.PrintInJsonFormat, c.PrintInMarkdownFormat} {
+		if printFormat {
+			printFormatCnt++
+		}
+	}
+	if printFormatCnt > 1 {
+		return fmt.Errorf("csv, json and markdown cannot be used together")
+	}
+
+	for _, encoding := range []struct {
+		option, name string
+		allowAuto    bool
+	}{
+		{"encoding1", c.Encoding1, true},
+		{"encoding2", c.Encoding2, true},
+		{"outputencoding", c.OutputEncoding, false},
+	} {
+		err = checkEncoding(encoding.option, encoding.name, encoding.allowAuto)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.UnicodeNormalize != "" {
+		_, err = getNormalizationForm(c.UnicodeNormalize)
+		if err != nil {
+			return err
+		}
+	}
+	if (c.UnicodeNormalize != "" || c.StripAccents) && c.Method == MethodStringSorted {
+		return fmt.Errorf("unicode-normalize and strip-accents do not support the %s method", MethodStringSorted)
+	}
+
+	switch c.ArrangeMode {
+	case "", ArrangeModeStrict, ArrangeModeFront, ArrangeModeBack:
+	default:
+		return fmt.Errorf("unsupported arrange-mode %s", c.ArrangeMode)
+	}
+
+	_, err = ParseSortKeys(c.SortBy)
+	if err != nil {
+		return err
+	}
+
+	if c.Combine && c.TUI {
+		return fmt.Errorf("combine and tui cannot be used together")
+	}
+
+	if c.Watch {
+		if c.TUI {
+			return fmt.Errorf("watch and tui cannot be used together")
+		}
+		if c.OutputDir != "" {
+			return fmt.Errorf("watch and outputdir cannot be used together")
+		}
+		if c.WatchInterval <= 0 {
+			return fmt.Errorf("watchinterval must be positive")
+		}
+	}
+
+	_, err = ParseAggregates(c.Aggregates)
+	if err != nil {
+		return err
+	}
+	if c.Tolerance < 0 {
+		return fmt.Errorf("tolerance must not be negative")
+	}
+	if c.Function != FunctionStringAggregate && c.Aggregates != nil {
+		return fmt.Errorf("aggregates require the %s function", FunctionStringAggregate)
+	}
+	if c.Function != FunctionStringAggregate && c.Function != FunctionStringReconcile && c.Tolerance != 0 {
+		return fmt.Errorf("tolerance requires the %s or %s function", FunctionStringAggregate, FunctionStringReconcile)
+	}
+
+	switch c.Function {
+	case FunctionStringCommon:
+	case FunctionStringDifferent:
+	case FunctionStringAggregate:
+		if len(c.KeyColumns) == 0 || len(c.Aggregates) == 0 {
+			return fmt.Errorf("the %s function requires keycolumns and aggregates", FunctionStringAggregate)
+		}
+		if c.Method == MethodStringSorted {
+			return fmt.Errorf("the %s function does not support the %s method", c.Function, MethodStringSorted)
+		}
+		if c.ColumnsToUse != nil || c.ColumnsToIgnore != nil || c.UseCommonColumns {
+			return fmt.Errorf("the %s function compares the aggregates and does not support usecolumns, ignorecolumns and usecommoncolumns", FunctionStringAggregate)
+		}
+	case FunctionStringStats, FunctionStringSchemaDiff, FunctionStringReconcile:
+		if c.Function == FunctionStringStats && len(c.KeyColumns) == 0 {
+			return fmt.Errorf("the %s function requires keycolumns", FunctionStringStats)
+		}
+		// The reconcile function always reads the files in a single pass.
+		if c.Method == MethodStringSorted && c.Function != FunctionStringReconcile {
+			return fmt.Errorf("the %s function does not support the %s method", c.Function, MethodStringSorted)
+		}
+		if c.Combine {
+			return fmt.Errorf("the %s function does not support combine", c.Function)
+		}
+		if c.TUI {
+			return fmt.Errorf("the %s function does not support tui", c.Function)
+		}
+		if c.Watch {
+			return fmt.Errorf("the %s function does not support watch", c.Function)
+		}
+		if c.SortBy != nil {
+			return fmt.Errorf("the %s function does not support sortby", c.Function)
+		}
+	case "":
+		return fmt.Errorf("function must be given")
+	default:
+		return fmt.Errorf("unsupported function %s", c.Function)
+	}
+
+	return nil
+}
+
+// Returns a new flag set with the command-line flags bound to the fields of cfg.
+func newFlagSet(cfg *Config, noHeader *bool) *pflag.FlagSet {
+	flags := pflag.NewFlagSet("csvcheckcli", pflag.ContinueOnError)
+	flags.StringVarP(&cfg.InputDir, "inputdir", "d", "", "The directory containing the input files. This will be prepended to the input file paths. Must be given.")
+	flags.StringSliceVarP(&cfg.Files, "files", "f", []string{}, "The input files paths to compare. 2 should be provided.")
+	flags.StringVarP(&cfg.Method, "method", "m", "set", "The method to use for comparison. Options: match, set, direct, sorted. The sorted method streams files already sorted by the compared columns and pairs rows like match. By default, set is used.")
+	flags.StringVarP(&cfg.Function, "function", "F", "", "The function to use for comparison. Options: common, different, stats, schemadiff, aggregate, reconcile. The stats function pairs rows by keycolumns and reports how often each compared column differs. The aggregate function groups rows by keycolumns and reports the groups whose aggregates differ. The reconcile function compares row counts, empty values, sums of numeric columns and an order-independent checksum in a single pass and exits with status 1 if any of them differ. The schemadiff function reports added, removed, renamed and reordered columns and exits with status 1 if columns were removed or renamed. A function must be given.")
+	flags.BoolVarP(&cfg.KeepIndex, "keepindex", "k", false, fmt.Sprintf("Whether to keep the indices from the original csv of the rows in the result (%s column will be added).", IndexColumnName))
+	flags.StringVarP(&cfg.OutputDir, "outputdir", "o", "", "The directory to write the output files to. It is created if it does not exist.")
+	flags.BoolVarP(&cfg.AddTimestamp, "addtimestamp", "t", false, "Whether or not to add a timestamp to the output file name.")
+	flags.StringSliceVarP(&cfg.ColumnsToUse, "usecolumns", "c", nil, "The columns to use for comparison. Glob patterns such as audit_* and regular expressions prefixed with re: select every matching column.")
+	flags.StringSliceVarP(&cfg.ColumnsToIgnore, "ignorecolumns", "i", nil, "The columns to ignore for comparison. Glob patterns and re: regular expressions are allowed.")
+	flags.BoolVarP(&cfg.AutoAlign, "autoalign", "a", false, "Whether or not to auto align the columns of the csv files. Common columns will be aligned on the left side.")
+	flags.BoolVarP(&cfg.UseCommonColumns, "usecommoncolumns", "C", false, "Whether to use all the common columns between the csv files for comparison.")
+	flags.StringSliceVarP(&cfg.ColumnsToKeep, "keepcolumns", "K", nil, "The columns to keep in the output. Glob patterns and re: regular expressions are allowed.")
+	flags.StringSliceVarP(&cfg.ColumnsToDelete, "deletecolumns", "D", nil, "The columns to delete in the output. Glob patterns and re: regular expressions are allowed.")
+	flags.StringSliceVarP(&cfg.ColumnsArrangement1, "columnsarrangement1", "r", nil, "An arrangement for the columns in the first output.")
+	flags.StringSliceVarP(&cfg.ColumnsArrangement2, "columnsarrangement2", "R", nil, "An arrangement for the columns in the second output.")
+	flags.StringSliceVar(&cfg.SortBy, "sortby", nil, fmt.Sprintf("The columns to sort the result rows by, as column[:%s|%s][:%s|%s|%s|%s], such as amount:desc,name. Without a type, it is detected from the values. Rows that compare equal keep their order, and empty values come last.", SortAscending, SortDescending, SortTypeNumeric, SortTypeDate, SortTypeString, SortTypeNatural))
+	flags.StringSliceVar(&cfg.Aggregates, "aggregates", nil, fmt.Sprintf("The aggregates the aggregate function computes for each group, as function:column with the functions %s, %s, %s, %s and %s, such as sum:amount,count. %s without a column counts the rows of the group.", AggregateSum, AggregateCount, AggregateMin, AggregateMax, AggregateAvg, AggregateCount))
+	flags.Float64Var(&cfg.Tolerance, "tolerance", 0, "The largest difference between the aggregates of a group, or the sums of a column for the reconcile function, in both files for which they are still taken as equal.")
+	flags.StringVar(&cfg.ArrangeMode, "arrange-mode", ArrangeModeStrict, fmt.Sprintf("How columns missing from an arrangement are handled. Options: %s, %s, %s. With %s, they keep their order after the arranged columns, with %s before them, and with %s, the arrangement must list every column.", ArrangeModeFront, ArrangeModeBack, ArrangeModeStrict, ArrangeModeFront, ArrangeModeBack, ArrangeModeStrict))
+	flags.BoolVarP(&cfg.PrintInCsvFormat, "csv", "p", false, "Whether to print the output in csv format. By default, the output is printed in a columns-aligned.")
+	flags.IntVarP(&cfg.PrettyFormatMaxLength, "prettyformatmaxlength", "l", -1, "The maximum length before truncation of a column entry when printing in pretty format. Negative values mean no limit. By default, there is no limit.")
+	flags.BoolVar(noHeader, "noheader", false, fmt.Sprintf("Whether both csv files have no header row. Columns will be named %s1, %s2, ... and can also be referenced by position (#1, #2, ...).", GeneratedColumnPrefix, GeneratedColumnPrefix))
+	flags.BoolVar(&cfg.NoHeader1, "noheader1", false, "Whether the first csv file has no header row.")
+	flags.BoolVar(&cfg.NoHeader2, "noheader2", false, "Whether the second csv file has no header row.")
+	flags.BoolVarP(&cfg.NormalizeHeaders, "normalizeheaders", "n", false, "Whether to match headers and given column names case-insensitively, ignoring surrounding whitespace, byte order marks and the kind of separators used (spaces, underscores, hyphens, dots). Headers in the output are normalized.")
+	flags.IntVar(&cfg.Limit, "limit", 0, "The maximum number of result rows to print for each file. The output files still contain all rows. Values of 0 or less mean no limit.")
+	flags.BoolVar(&cfg.Head, "head", false, "Whether to print the first rows when a limit is given. This is the default.")
+	flags.BoolVar(&cfg.Tail, "tail", false, "Whether to print the last rows when a limit is given.")
+	flags.IntVar(&cfg.Sample, "sample", 0, "The number of randomly sampled result rows to print for each file. The output files still contain all rows. Values of 0 or less mean no sampling.")
+	flags.Int64Var(&cfg.Seed, "seed", 0, "The seed used for sampling. The same seed gives the same sample.")
+	flags.IntVar(&cfg.Workers, "workers", runtime.NumCPU(), "The number of goroutines used for hashing rows. Values of 1 or less hash the rows in a single goroutine.")
+	flags.BoolVar(&cfg.Progress, "progress", false, "Whether to show the progress of reading and hashing the rows on stderr.")
+	flags.BoolVar(&cfg.EmitHash, "emit-hash", false, fmt.Sprintf("Whether to add a fingerprint of the compared columns of each row to the result (%s column will be added). The fingerprint does not depend on the order of the columns.", HashColumnName))
+	flags.StringSliceVar(&cfg.KeyColumns, "keycolumns", nil, "The columns identifying a row, which the stats function pairs rows by and the aggregate function groups rows by. Required by both. In tui, the rows of both files with the same key are shown side by side.")
+	flags.BoolVar(&cfg.PrintInJsonFormat, "json", false, "Whether to print the output in json format.")
+	flags.BoolVar(&cfg.PrintInMarkdownFormat, "markdown", false, "Whether to print the output as markdown tables preceded by a summary, for pasting into pull request comments. Cells are truncated like in pretty format.")
+	addWriteFlags(flags, &cfg.Overwrite, &cfg.NoClobber, &cfg.FileMode)
+	flags.StringVar(&cfg.OutputName, "outputname", "", "A template for the names of the output files, such as {function}_{method}_{file}_{date:20060102}.{ext}. Placeholders: {function}, {method}, {file} (the input file name without extension), {file1}, {file2}, {ext}, {dir} (the input directory name), {runid} (random for every run) and {date} with an optional go time layout. By default, csvcheck_{file}.csv is used.")
+	flags.BoolVar(&cfg.Combine, "combine", false, fmt.Sprintf("Whether to print and write the results of both files as a single table, with a %s column holding the file name of each row. Columns missing from the results of one of the files are left empty. Implies keepindex.", SourceColumnName))
+	flags.BoolVar(&cfg.TUI, "tui", false, fmt.Sprintf("Whether to browse the results of both files side by side in the terminal, with search, column hiding and jumping to a row by its %s. If the output is not a terminal, the results are printed as usual.", IndexColumnName))
+	flags.BoolVar(&cfg.Watch, "watch", false, "Whether to keep checking the input files for changes after printing the results, and to compare them again and print how the results changed whenever they do. Stop with Ctrl+C.")
+	flags.DurationVar(&cfg.WatchInterval, "watchinterval", DefaultWatchInterval, "How often to check the input files for changes in watch mode.")
+	flags.StringVar(&cfg.Encoding1, "encoding1", "", fmt.Sprintf("The encoding of the first csv file, such as %s, %s or %s, which it is transcoded to UTF-8 from before comparing. With %s, the encoding is detected from the byte order mark or the start of the content, and an error is given if content detected as UTF-8 turns out not to be. By default, the file is read as UTF-8 as it is.", EncodingWindows1252, EncodingUTF16LE, "iso-8859-1", EncodingAuto))
+	flags.StringVar(&cfg.Encoding2, "encoding2", "", "The encoding of the second csv file, like encoding1.")
+	flags.StringVar(&cfg.OutputEncoding, "outputencoding", "", "The encoding of the output files. By default, they are written in UTF-8.")
+	flags.StringVar(&cfg.UnicodeNormalize, "unicode-normalize", "", "The unicode normalization form to compare values in, one of NFC, NFD, NFKC and NFKD, so that composed and decomposed characters such as é match. The output keeps the original values.")
+	flags.BoolVar(&cfg.StripAccents, "strip-accents", false, "Whether to compare values without their accents, so that e and é match. The output keeps the original values.")
+	return flags
+}
+
+// Parses the command-line arguments (without the program name) into a Config using
+// a flag set of its own, so it can be called any number of times. If help is
+// requested, pflag.ErrHelp is returned.
+func ParseArgs(args []string) (Config, error) {
+	var cfg Config
+	var noHeader bool
+	flags := newFlagSet(&cfg, &noHeader)
+	err := flags.Parse(args)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if noHeader {
+		cfg.NoHeader1 = true
+		cfg.NoHeader2 = true
+	}
+
+	err = cfg.validateFiles()
+	if err != nil {
+		return Config{}, err
+	}
+	err = cfg.Validate()
+	if err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,154 @@
+package csvcheckcli_test
+
+import (
+	"context"
+	"csvcheckcli/csvcheckcli"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseArgsProperAndImproperInputs(t *testing.T) {
+	for i, data := range []struct {
+		args        []string
+		expectError bool
+	}{
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "common"}, expectError: false},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "different", "-m", "sorted", "--noheader"}, expectError: false},
+		{args: []string{"-d", "dir", "-f", "file1.csv", "-F", "common"}, expectError: true},
+		{args: []string{"-f", "file1.csv,file2.csv", "-F", "common"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "common", "-c", "a", "-C"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "common", "--nosuchflag"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "different", "--combine"}, expectError: false},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "schemadiff", "--combine"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "different", "--tui", "--keycolumns", "id"}, expectError: false},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "stats", "--tui", "--keycolumns", "id"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "different", "--tui", "--combine"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "common", "--watch", "--watchinterval", "500ms"}, expectError: false},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "common", "--watch", "-o", "out"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "common", "--watch", "--watchinterval", "0s"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "common", "--encoding1", "auto", "--encoding2", "windows-1252", "--outputencoding", "utf-16le"}, expectError: false},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "common", "--encoding1", "nosuchencoding"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "common", "--outputencoding", "auto"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "common", "--unicode-normalize", "nfkc", "--strip-accents"}, expectError: false},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "common", "--unicode-normalize", "NFX"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "common", "-m", "sorted", "--strip-accents"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "common", "-r", "_ind", "--arrange-mode", "front"}, expectError: false},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "common", "--arrange-mode", "middle"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "different", "--sortby", "amount:desc:numeric,name"}, expectError: false},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "different", "--sortby", ":desc"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "schemadiff", "--sortby", "name"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "aggregate", "--keycolumns", "account", "--aggregates", "sum:amount,count", "--tolerance", "0.01"}, expectError: false},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "aggregate", "--keycolumns", "account"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "aggregate", "--aggregates", "sum:amount"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "aggregate", "--keycolumns", "account", "--aggregates", "sum:amount", "-c", "amount"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "different", "--tolerance", "1"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "aggregate", "--keycolumns", "account", "--aggregates", "sum:amount", "--tolerance", "-1"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "reconcile", "-c", "amount", "--tolerance", "0.01", "-m", "sorted"}, expectError: false},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "reconcile", "--aggregates", "sum:amount"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "reconcile", "--combine"}, expectError: true},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		_, err := csvcheckcli.ParseArgs(data.args)
+		if data.expectError {
+			assert.NotNil(t, err, indexString)
+		} else {
+			assert.Nil(t, err, indexString)
+		}
+	}
+}
+
+func TestParseArgsValues(t *testing.T) {
+	cfg, err := csvcheckcli.ParseArgs([]string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "common", "-k", "-c", "a,b", "--noheader"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "dir", cfg.InputDir)
+	assert.Equal(t, []string{"file1.csv", "file2.csv"}, cfg.Files)
+	assert.Equal(t, csvcheckcli.MethodStringSet, cfg.Method)
+	assert.True(t, cfg.KeepIndex)
+	assert.Equal(t, []string{"a", "b"}, cfg.ColumnsToUse)
+	assert.Nil(t, cfg.ColumnsToIgnore)
+	assert.True(t, cfg.NoHeader1)
+	assert.True(t, cfg.NoHeader2)
+	assert.Equal(t, -1, cfg.PrettyFormatMaxLength)
+}
+
+func TestNewConfigOptionsMatchFlags(t *testing.T) {
+	cfg, err := csvcheckcli.ParseArgs([]string{
+		"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "aggregate", "--keycolumns", "k", "--aggregates", "sum:v",
+		"--tolerance", "0.5", "--sortby", "sum_v:desc", "--arrange-mode", "front", "--encoding1", "auto", "--strip-accents",
+		"--limit", "5", "--tail", "-o", "out", "--overwrite", "--outputencoding", "windows-1252",
+	})
+	assert.Nil(t, err)
+
+	expected := csvcheckcli.NewConfig(
+		csvcheckcli.WithInputDir("dir"),
+		csvcheckcli.WithFiles("file1.csv", "file2.csv"),
+		csvcheckcli.WithFunction(csvcheckcli.FunctionStringAggregate),
+		csvcheckcli.WithKeyColumns("k"),
+		csvcheckcli.WithAggregates("sum:v"),
+		csvcheckcli.WithTolerance(0.5),
+		csvcheckcli.WithSortBy("sum_v:desc"),
+		csvcheckcli.WithArrangeMode(csvcheckcli.ArrangeModeFront),
+		csvcheckcli.WithEncodings(csvcheckcli.EncodingAuto, ""),
+		csvcheckcli.WithStripAccents(true),
+		csvcheckcli.WithLimit(5),
+		csvcheckcli.WithTail(true),
+		csvcheckcli.WithOutputDir("out"),
+		csvcheckcli.WithOverwrite(true),
+		csvcheckcli.WithOutputEncoding(csvcheckcli.EncodingWindows1252),
+	)
+	assert.Equal(t, expected, cfg)
+}
+
+func TestConfigUserInputRoundTrip(t *testing.T) {
+	cfg := csvcheckcli.NewConfig(
+		csvcheckcli.WithFunction(csvcheckcli.FunctionStringDifferent),
+		csvcheckcli.WithColumnsToUse("a"),
+		csvcheckcli.WithColumnsArrangements([]string{"b", "a"}, nil),
+	)
+
+	assert.Equal(t, cfg, cfg.UserInput().Config())
+	assert.Equal(t, csvcheckcli.Config{}, csvcheckcli.UserInput{}.Config())
+}
+
+func TestCompareDifferentMatchKeepIndex(t *testing.T) {
+	cfg := csvcheckcli.NewConfig(
+		csvcheckcli.WithMethod(csvcheckcli.MethodStringMatch),
+		csvcheckcli.WithFunction(csvcheckcli.FunctionStringDifferent),
+		csvcheckcli.WithKeepIndex(true),
+		csvcheckcli.WithAutoAlign(true),
+	)
+
+	src1 := strings.NewReader(`a,b
+1,2
+3,4
+`)
+	src2 := strings.NewReader(`b,a
+2,1
+5,6
+`)
+	result, err := csvcheckcli.Compare(context.Background(), src1, src2, cfg)
+
+	expected1 := Get2DArrayFromCsvString(fmt.Sprintf(`
+a,b,%s
+3,4,2
+`, csvcheckcli.IndexColumnName))
+
+	expected2 := Get2DArrayFromCsvString(fmt.Sprintf(`
+a,b,%s
+6,5,2
+`, csvcheckcli.IndexColumnName))
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected1, result.Rows1)
+	assert.Equal(t, expected2, result.Rows2)
+}
+
+func TestCompareInvalidConfig(t *testing.T) {
+	_, err := csvcheckcli.Compare(context.Background(), strings.NewReader("a\n1\n"), strings.NewReader("a\n1\n"), csvcheckcli.NewConfig())
+
+	assert.NotNil(t, err)
+}
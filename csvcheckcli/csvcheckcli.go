@@ -1,14 +1,18 @@
 package csvcheckcli
 
 import (
+	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/BrianWeiHaoMa/csvcheck"
-
-	"github.com/spf13/pflag"
 )
 
 const IndexColumnName = "_ind"
@@ -16,9 +20,18 @@ const IndexColumnName = "_ind"
 const MethodStringMatch = "match"
 const MethodStringSet = "set"
 const MethodStringDirect = "direct"
+const MethodStringSorted = "sorted"
 
 const FunctionStringCommon = "common"
 const FunctionStringDifferent = "different"
+const FunctionStringStats = "stats"
+const FunctionStringSchemaDiff = "schemadiff"
+const FunctionStringAggregate = "aggregate"
+const FunctionStringReconcile = "reconcile"
+
+const ArrangeModeStrict = "strict"
+const ArrangeModeFront = "front"
+const ArrangeModeBack = "back"
 
 var MethodMappings = map[string]int{
 	MethodStringMatch:  csvcheck.MethodMatch,
@@ -26,150 +39,266 @@ var MethodMappings = map[string]int{
 	MethodStringDirect: csvcheck.MethodDirect,
 }
 
+// For holding the options given by the user as pointers, the way the flag
+// package returns them. See Config for a value-typed equivalent. In json, the
+// options are named after their command-line flags.
 type UserInput struct {
-	InputDir              *string
-	Files                 *[]string
-	Method                *string
-	Function              *string
-	KeepIndex             *bool
-	OutputDir             *string
-	AddTimestamp          *bool
-	ColumnsToUse          *[]string
-	ColumnsToIgnore       *[]string
-	AutoAlign             *bool
-	UseCommonColumns      *bool
-	ColumnsToKeep         *[]string
-	ColumnsToDelete       *[]string
-	ColumnsArrangement1   *[]string
-	ColumnsArrangement2   *[]string
-	PrintInCsvFormat      *bool
-	PrettyFormatMaxLength *int
+	InputDir              *string        `json:"inputdir,omitempty"`
+	Files                 *[]string      `json:"files,omitempty"`
+	Method                *string        `json:"method,omitempty"`
+	Function              *string        `json:"function,omitempty"`
+	KeepIndex             *bool          `json:"keepindex,omitempty"`
+	OutputDir             *string        `json:"outputdir,omitempty"`
+	AddTimestamp          *bool          `json:"addtimestamp,omitempty"`
+	ColumnsToUse          *[]string      `json:"usecolumns,omitempty"`
+	ColumnsToIgnore       *[]string      `json:"ignorecolumns,omitempty"`
+	AutoAlign             *bool          `json:"autoalign,omitempty"`
+	UseCommonColumns      *bool          `json:"usecommoncolumns,omitempty"`
+	ColumnsToKeep         *[]string      `json:"keepcolumns,omitempty"`
+	ColumnsToDelete       *[]string      `json:"deletecolumns,omitempty"`
+	ColumnsArrangement1   *[]string      `json:"columnsarrangement1,omitempty"`
+	ColumnsArrangement2   *[]string      `json:"columnsarrangement2,omitempty"`
+	PrintInCsvFormat      *bool          `json:"csv,omitempty"`
+	PrettyFormatMaxLength *int           `json:"prettyformatmaxlength,omitempty"`
+	NormalizeHeaders      *bool          `json:"normalizeheaders,omitempty"`
+	NoHeader1             *bool          `json:"noheader1,omitempty"`
+	NoHeader2             *bool          `json:"noheader2,omitempty"`
+	Limit                 *int           `json:"limit,omitempty"`
+	Head                  *bool          `json:"head,omitempty"`
+	Tail                  *bool          `json:"tail,omitempty"`
+	Sample                *int           `json:"sample,omitempty"`
+	Seed                  *int64         `json:"seed,omitempty"`
+	Workers               *int           `json:"workers,omitempty"`
+	Progress              *bool          `json:"progress,omitempty"`
+	EmitHash              *bool          `json:"emit-hash,omitempty"`
+	KeyColumns            *[]string      `json:"keycolumns,omitempty"`
+	PrintInJsonFormat     *bool          `json:"json,omitempty"`
+	PrintInMarkdownFormat *bool          `json:"markdown,omitempty"`
+	Overwrite             *bool          `json:"overwrite,omitempty"`
+	NoClobber             *bool          `json:"no-clobber,omitempty"`
+	FileMode              *os.FileMode   `json:"filemode,omitempty"`
+	OutputName            *string        `json:"outputname,omitempty"`
+	Combine               *bool          `json:"combine,omitempty"`
+	TUI                   *bool          `json:"tui,omitempty"`
+	Watch                 *bool          `json:"watch,omitempty"`
+	WatchInterval         *time.Duration `json:"watchinterval,omitempty"`
+	Encoding1             *string        `json:"encoding1,omitempty"`
+	Encoding2             *string        `json:"encoding2,omitempty"`
+	OutputEncoding        *string        `json:"outputencoding,omitempty"`
+	UnicodeNormalize      *string        `json:"unicode-normalize,omitempty"`
+	StripAccents          *bool          `json:"strip-accents,omitempty"`
+	ArrangeMode           *string        `json:"arrange-mode,omitempty"`
+	SortBy                *[]string      `json:"sortby,omitempty"`
+	Aggregates            *[]string      `json:"aggregates,omitempty"`
+	Tolerance             *float64       `json:"tolerance,omitempty"`
 }
 
+// Parses the command-line arguments into a UserInput if input is nil, and
+// validates the user input otherwise.
 func ParseUserInput(input *UserInput) (UserInput, error) {
-	var res UserInput
 	if input == nil {
-		res = UserInput{}
-		res.InputDir = pflag.StringP("inputdir", "d", "", "The directory containing the input files. This will be prepended to the input file paths. Must be given.")
-		res.Files = pflag.StringSliceP("files", "f", []string{}, "The input files paths to compare. 2 should be provided.")
-		res.Method = pflag.StringP("method", "m", "set", "The method to use for comparison. Options: match, set, direct. By default, set is used.")
-		res.Function = pflag.StringP("function", "F", "", "The function to use for comparison. Options: common, different. A function must be given.")
-		res.KeepIndex = pflag.BoolP("keepindex", "k", false, fmt.Sprintf("Whether to keep the indices from the original csv of the rows in the result (%s column will be added).", IndexColumnName))
-		res.OutputDir = pflag.StringP("outputdir", "o", "", "The directory to write the output files to.")
-		res.AddTimestamp = pflag.BoolP("addtimestamp", "t", false, "Whether or not to add a timestamp to the output file name.")
-		res.ColumnsToUse = pflag.StringSliceP("usecolumns", "c", nil, "The columns to use for comparison.")
-		res.ColumnsToIgnore = pflag.StringSliceP("ignorecolumns", "i", nil, "The columns to ignore for comparison.")
-		res.AutoAlign = pflag.BoolP("autoalign", "a", false, "Whether or not to auto align the columns of the csv files. Common columns will be aligned on the left side.")
-		res.UseCommonColumns = pflag.BoolP("usecommoncolumns", "C", false, "Whether to use all the common columns between the csv files for comparison.")
-		res.ColumnsToKeep = pflag.StringSliceP("keepcolumns", "K", nil, "The columns to keep in the output.")
-		res.ColumnsToDelete = pflag.StringSliceP("deletecolumns", "D", nil, "The columns to delete in the output.")
-		res.ColumnsArrangement1 = pflag.StringSliceP("columnsarrangement1", "r", nil, "An arrangement for the columns in the first output.")
-		res.ColumnsArrangement2 = pflag.StringSliceP("columnsarrangement2", "R", nil, "An arrangement for the columns in the second output.")
-		res.PrintInCsvFormat = pflag.BoolP("csv", "p", false, "Whether to print the output in csv format. By default, the output is printed in a columns-aligned.")
-		res.PrettyFormatMaxLength = pflag.IntP("prettyformatmaxlength", "l", -1, "The maximum length before truncation of a column entry when printing in pretty format. Negative values mean no limit. By default, there is no limit.")
-
-		pflag.Parse()
-	} else {
-		res = *input
+		cfg, err := ParseArgs(os.Args[1:])
+		if err != nil {
+			return UserInput{}, err
+		}
+		return cfg.UserInput(), nil
 	}
 
-	if *res.InputDir == "" {
-		return UserInput{}, fmt.Errorf("inputdir must be given")
+	cfg := input.Config()
+	err := cfg.validateFiles()
+	if err != nil {
+		return UserInput{}, err
 	}
+	err = cfg.Validate()
+	if err != nil {
+		return UserInput{}, err
+	}
+
+	return *input, nil
+}
 
-	if len(*res.Files) != 2 {
-		return UserInput{}, fmt.Errorf("exactly 2 file paths needed")
+// Adds the index to the row on the right side.
+func addIndexToRow(row []csvcheck.StringHashable, index int) []csvcheck.StringHashable {
+	newLength := len(row) + 1
+	res := make([]csvcheck.StringHashable, newLength)
+	res[newLength-1] = csvcheck.BasicStringHashable(fmt.Sprintf("%d", index))
+	copy(res[:newLength-1], row)
+	return res
+}
+
+// Holds the columns given by the user resolved against the headers of the csv arrays.
+type resolvedColumns struct {
+	toUse        []csvcheck.StringHashable
+	toIgnore     []csvcheck.StringHashable
+	toKeep       []csvcheck.StringHashable
+	toDelete     []csvcheck.StringHashable
+	arrangement1 []csvcheck.StringHashable
+	arrangement2 []csvcheck.StringHashable
+	sortBy       []SortKey
+}
+
+// Returns the csv arrays with their header rows generated or normalized based off of the config.
+func prepareCsvArrays(csvArray1, csvArray2 [][]csvcheck.StringHashable, cfg Config) ([][]csvcheck.StringHashable, [][]csvcheck.StringHashable, error) {
+	if cfg.NoHeader1 {
+		csvArray1 = addGeneratedHeaderRow(csvArray1)
+	}
+	if cfg.NoHeader2 {
+		csvArray2 = addGeneratedHeaderRow(csvArray2)
 	}
 
-	if _, exists := MethodMappings[*res.Method]; !exists {
-		return UserInput{}, fmt.Errorf("unsupported method %s", *res.Method)
+	if cfg.NormalizeHeaders {
+		csvArray1 = normalizeHeaderRow(csvArray1)
+		csvArray2 = normalizeHeaderRow(csvArray2)
 	}
 
-	columnsCompInputCnt := 0
-	if *res.ColumnsToUse != nil {
-		columnsCompInputCnt++
+	if len(csvArray1) == 0 || len(csvArray2) == 0 {
+		return nil, nil, fmt.Errorf("empty array")
 	}
-	if *res.ColumnsToIgnore != nil {
-		columnsCompInputCnt++
+	return csvArray1, csvArray2, nil
+}
+
+// Resolves the columns given by the user against the headers of the csv arrays.
+// Positional references are resolved against the input headers, so the columns
+// added to the results, such as _ind, cannot be given by position.
+func resolveInputColumns(header1, header2 []csvcheck.StringHashable, cfg Config) (resolvedColumns, error) {
+	normalizeHeaders := cfg.NormalizeHeaders
+
+	var columns resolvedColumns
+	var err error
+	columns.toUse, err = resolveColumns("usecolumns", cfg.ColumnsToUse, normalizeHeaders, header1, header2)
+	if err != nil {
+		return resolvedColumns{}, err
 	}
-	if *res.UseCommonColumns {
-		columnsCompInputCnt++
+	columns.toIgnore, err = resolveColumns("ignorecolumns", cfg.ColumnsToIgnore, normalizeHeaders, header1, header2)
+	if err != nil {
+		return resolvedColumns{}, err
+	}
+	columns.toKeep, err = resolveColumns("keepcolumns", cfg.ColumnsToKeep, normalizeHeaders, header1, header2)
+	if err != nil {
+		return resolvedColumns{}, err
 	}
-	if columnsCompInputCnt > 1 {
-		return UserInput{}, fmt.Errorf("usecolumns, ignorecolumns, and usecommoncolumns cannot be used together")
+	columns.toDelete, err = resolveColumns("deletecolumns", cfg.ColumnsToDelete, normalizeHeaders, header1, header2)
+	if err != nil {
+		return resolvedColumns{}, err
+	}
+	columns.arrangement1, err = resolveColumns("columnsarrangement1", cfg.ColumnsArrangement1, normalizeHeaders, header1)
+	if err != nil {
+		return resolvedColumns{}, err
+	}
+	columns.arrangement2, err = resolveColumns("columnsarrangement2", cfg.ColumnsArrangement2, normalizeHeaders, header2)
+	if err != nil {
+		return resolvedColumns{}, err
 	}
 
-	columnsResInputCnt := 0
-	if *res.ColumnsToKeep != nil {
-		columnsResInputCnt++
+	columns.sortBy, err = resolveSortKeys(cfg.SortBy, normalizeHeaders, header1, header2)
+	if err != nil {
+		return resolvedColumns{}, err
 	}
-	if *res.ColumnsToDelete != nil {
-		columnsResInputCnt++
+
+	columns.toUse, err = expandColumnPatterns("usecolumns", columns.toUse, header1, header2)
+	if err != nil {
+		return resolvedColumns{}, err
 	}
-	if columnsResInputCnt > 1 {
-		return UserInput{}, fmt.Errorf("keepcolumns and deletecolumns cannot be used together")
+	columns.toIgnore, err = expandColumnPatterns("ignorecolumns", columns.toIgnore, header1, header2)
+	if err != nil {
+		return resolvedColumns{}, err
 	}
 
-	switch *res.Function {
-	case FunctionStringCommon:
-	case FunctionStringDifferent:
-	case "":
-		return UserInput{}, fmt.Errorf("function must be given")
-	default:
-		return UserInput{}, fmt.Errorf("unsupported function %s", *res.Function)
+	err = checkColumnsExist("usecolumns", columns.toUse, header1, header2)
+	if err != nil {
+		return resolvedColumns{}, err
+	}
+	err = checkColumnsExist("ignorecolumns", columns.toIgnore, header1, header2)
+	if err != nil {
+		return resolvedColumns{}, err
 	}
 
-	return res, nil
-}
+	if cfg.UseCommonColumns {
+		columns.toUse, err = csvcheck.GetCommonColumns([][]csvcheck.StringHashable{header1}, [][]csvcheck.StringHashable{header2})
+		if err != nil {
+			return resolvedColumns{}, err
+		}
+	}
 
-// Adds the index to the row on the right side.
-func addIndexToRow(row []csvcheck.StringHashable, index int) []csvcheck.StringHashable {
-	newLength := len(row) + 1
-	res := make([]csvcheck.StringHashable, newLength)
-	res[newLength-1] = csvcheck.BasicStringHashable(fmt.Sprintf("%d", index))
-	copy(res[:newLength-1], row)
-	return res
+	return columns, nil
 }
 
 // Gets the result arrays based off of user input.
 func GetResArrays(csvArray1, csvArray2 [][]csvcheck.StringHashable, input UserInput) ([][]csvcheck.StringHashable, [][]csvcheck.StringHashable, error) {
-	columnsToUse := csvcheck.GetRowFromRow(*input.ColumnsToUse)
-	columnsToIgnore := csvcheck.GetRowFromRow(*input.ColumnsToIgnore)
+	return getResArrays(context.Background(), csvArray1, csvArray2, input.Config(), nil)
+}
 
-	var err error = nil
-	if *input.UseCommonColumns {
-		columnsToUse, err = csvcheck.GetCommonColumns(csvArray1, csvArray2)
-		if err != nil {
-			return nil, nil, err
-		}
+// Gets the result arrays based off of the config. The comparison stops early
+// with the context's error if it is cancelled while hashing rows in parallel.
+func getResArrays(ctx context.Context, csvArray1, csvArray2 [][]csvcheck.StringHashable, cfg Config, tracker *progressTracker) ([][]csvcheck.StringHashable, [][]csvcheck.StringHashable, error) {
+	csvArray1, csvArray2, err := prepareCsvArrays(csvArray1, csvArray2, cfg)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if *input.AutoAlign {
+	columns, err := resolveInputColumns(csvArray1[0], csvArray2[0], cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.AutoAlign {
 		csvArray1, csvArray2, err = csvcheck.AutoAlignCsvArrays(csvArray1, csvArray2)
 		if err != nil {
 			return nil, nil, err
 		}
 	}
 
+	if cfg.Method == MethodStringSorted {
+		return nil, nil, fmt.Errorf("the %s method streams the csv files, use GetResArraysSorted instead", MethodStringSorted)
+	}
+
 	options := csvcheck.Options{
-		Method:        MethodMappings[*input.Method],
-		UseColumns:    columnsToUse,
-		IgnoreColumns: columnsToIgnore,
+		Method:        MethodMappings[cfg.Method],
+		UseColumns:    columns.toUse,
+		IgnoreColumns: columns.toIgnore,
 	}
 
-	if *input.KeepIndex {
+	if cfg.keepsIndex() {
 		options.SortIndices = true
 	}
 
+	// The rows are compared by their normalized values if a normalization is given,
+	// and the results are made of the original rows afterwards.
+	normalizer, err := newValueNormalizer(cfg.UnicodeNormalize, cfg.StripAccents)
+	if err != nil {
+		return nil, nil, err
+	}
+	compared1, compared2 := csvArray1, csvArray2
+	if normalizer != nil {
+		compared1 = normalizeCsvArrayValues(csvArray1, normalizer)
+		compared2 = normalizeCsvArrayValues(csvArray2, normalizer)
+	}
+
 	var res1 = [][]csvcheck.StringHashable{}
 	var res2 = [][]csvcheck.StringHashable{}
 	var indices1 = []int{}
 	var indices2 = []int{}
-	switch *input.Function {
+	switch cfg.Function {
 	case FunctionStringCommon:
-		res1, res2, indices1, indices2, err = csvcheck.GetCommonRows(csvArray1, csvArray2, options)
+		if cfg.Workers > 1 {
+			res1, res2, indices1, indices2, err = getRowsParallel(ctx, compared1, compared2, options, cfg.Workers, true, tracker)
+		} else {
+			res1, res2, indices1, indices2, err = csvcheck.GetCommonRows(compared1, compared2, options)
+		}
 	case FunctionStringDifferent:
-		res1, res2, indices1, indices2, err = csvcheck.GetDifferentRows(csvArray1, csvArray2, options)
+		if cfg.Workers > 1 {
+			res1, res2, indices1, indices2, err = getRowsParallel(ctx, compared1, compared2, options, cfg.Workers, false, tracker)
+		} else {
+			res1, res2, indices1, indices2, err = csvcheck.GetDifferentRows(compared1, compared2, options)
+		}
+	case FunctionStringAggregate:
+		res1, res2, indices1, indices2, err = getAggregateResArrays(compared1, compared2, cfg)
+	case FunctionStringStats:
+		return nil, nil, fmt.Errorf("the %s function has no result arrays, use GetDifferenceStats instead", FunctionStringStats)
+	case FunctionStringSchemaDiff:
+		return nil, nil, fmt.Errorf("the %s function has no result arrays, use GetSchemaDiff instead", FunctionStringSchemaDiff)
+	case FunctionStringReconcile:
+		return nil, nil, fmt.Errorf("the %s function has no result arrays, use Reconcile instead", FunctionStringReconcile)
 	default:
 		return nil, nil, fmt.Errorf("unsupported function")
 	}
@@ -177,8 +306,24 @@ func GetResArrays(csvArray1, csvArray2 [][]csvcheck.StringHashable, input UserIn
 	if err != nil {
 		return nil, nil, err
 	}
+	// The aggregated rows are not rows of the csv arrays and keep the normalized keys.
+	if normalizer != nil && cfg.Function != FunctionStringAggregate {
+		res1 = restoreOriginalRows(res1, csvArray1, indices1)
+		res2 = restoreOriginalRows(res2, csvArray2, indices2)
+	}
+
+	if cfg.Workers <= 1 {
+		tracker.reportHashing(1, int64(len(csvArray1)-1), int64(len(csvArray1)-1))
+		tracker.reportHashing(2, int64(len(csvArray2)-1), int64(len(csvArray2)-1))
+	}
 
-	if *input.KeepIndex {
+	return finishResArrays(res1, res2, indices1, indices2, columns, cfg)
+}
+
+// Adds the indices and fingerprints to, sorts the rows of and keeps, deletes and
+// rearranges the columns of the result arrays based off of the config.
+func finishResArrays(res1, res2 [][]csvcheck.StringHashable, indices1, indices2 []int, columns resolvedColumns, cfg Config) ([][]csvcheck.StringHashable, [][]csvcheck.StringHashable, error) {
+	if cfg.keepsIndex() {
 		res1[0] = append(res1[0], csvcheck.BasicStringHashable(IndexColumnName))
 		for i := 1; i < len(res1); i++ {
 			res1[i] = addIndexToRow(res1[i], indices1[i])
@@ -189,8 +334,42 @@ func GetResArrays(csvArray1, csvArray2 [][]csvcheck.StringHashable, input UserIn
 		}
 	}
 
-	columnsToKeep := csvcheck.GetRowFromRow(*input.ColumnsToKeep)
-	columnsToDelete := csvcheck.GetRowFromRow(*input.ColumnsToDelete)
+	if cfg.EmitHash {
+		res1 = addHashColumn(res1, columns.toUse, columns.toIgnore)
+		res2 = addHashColumn(res2, columns.toUse, columns.toIgnore)
+	}
+
+	if len(columns.sortBy) > 0 {
+		sortColumns := make([]csvcheck.StringHashable, len(columns.sortBy))
+		for i, key := range columns.sortBy {
+			sortColumns[i] = csvcheck.BasicStringHashable(key.Column)
+		}
+		err := checkColumnsExist("sortby", sortColumns, res1[0], res2[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		res1 = SortResArray(res1, columns.sortBy)
+		res2 = SortResArray(res2, columns.sortBy)
+	}
+
+	// The columns to keep and delete are matched against the result columns, so that
+	// patterns can also match the index and hash columns.
+	columnsToKeep, err := expandColumnPatterns("keepcolumns", columns.toKeep, res1[0], res2[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	columnsToDelete, err := expandColumnPatterns("deletecolumns", columns.toDelete, res1[0], res2[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	err = checkColumnsExist("keepcolumns", columnsToKeep, res1[0], res2[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	err = checkColumnsExist("deletecolumns", columnsToDelete, res1[0], res2[0])
+	if err != nil {
+		return nil, nil, err
+	}
 	if columnsToKeep == nil {
 		columnsToKeep = append(res1[0], res2[0]...)
 	}
@@ -216,16 +395,14 @@ func GetResArrays(csvArray1, csvArray2 [][]csvcheck.StringHashable, input UserIn
 		return nil, nil, err
 	}
 
-	columnsArrangement1 := csvcheck.GetRowFromRow(*input.ColumnsArrangement1)
-	columnsArrangement2 := csvcheck.GetRowFromRow(*input.ColumnsArrangement2)
-	if *input.ColumnsArrangement1 != nil {
-		res1, err = csvcheck.RearrangeColumns(res1, columnsArrangement1)
+	if cfg.ColumnsArrangement1 != nil {
+		res1, err = arrangeColumns("columnsarrangement1", res1, columns.arrangement1, cfg.ArrangeMode)
 		if err != nil {
 			return nil, nil, err
 		}
 	}
-	if *input.ColumnsArrangement2 != nil {
-		res2, err = csvcheck.RearrangeColumns(res2, columnsArrangement2)
+	if cfg.ColumnsArrangement2 != nil {
+		res2, err = arrangeColumns("columnsarrangement2", res2, columns.arrangement2, cfg.ArrangeMode)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -234,36 +411,242 @@ func GetResArrays(csvArray1, csvArray2 [][]csvcheck.StringHashable, input UserIn
 	return res1, res2, nil
 }
 
-func ReadCsvFile(filePath string) [][]csvcheck.StringHashable {
-	file, err := os.Open(filePath)
+// Returns the result array with its columns rearranged. In the strict arrangement
+// mode, the arrangement must list every column. In the front and back modes, the
+// columns not listed keep their order after or before the listed ones.
+func arrangeColumns(flagName string, res [][]csvcheck.StringHashable, arrangement []csvcheck.StringHashable, mode string) ([][]csvcheck.StringHashable, error) {
+	arrangement, err := expandColumnPatterns(flagName, arrangement, res[0])
 	if err != nil {
-		log.Panic(err)
+		return nil, err
 	}
-	defer file.Close()
+	err = checkColumnsExist(flagName, arrangement, res[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == ArrangeModeFront || mode == ArrangeModeBack {
+		listed := make(map[string]bool)
+		for _, column := range arrangement {
+			listed[column.StringHash()] = true
+		}
+		unlisted := []csvcheck.StringHashable{}
+		for _, column := range res[0] {
+			if !listed[column.StringHash()] {
+				unlisted = append(unlisted, column)
+			}
+		}
+		if mode == ArrangeModeFront {
+			arrangement = append(arrangement[:len(arrangement):len(arrangement)], unlisted...)
+		} else {
+			arrangement = append(unlisted, arrangement...)
+		}
+	}
+	return csvcheck.RearrangeColumns(res, arrangement)
+}
+
+// Returned by Compare when the data of a source cannot be read, as opposed to the
+// data being invalid.
+type ReadError struct {
+	File int // 1 or 2 for the first or second source.
+	Err  error
+}
+
+func (e *ReadError) Error() string {
+	return fmt.Sprintf("cannot read file %d: %v", e.File, e.Err)
+}
+
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
+
+// A reader wrapping the errors of the underlying reader, other than io.EOF, in a ReadError.
+type sourceReader struct {
+	reader io.Reader
+	file   int
+}
+
+func (r sourceReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err != nil && err != io.EOF {
+		err = &ReadError{File: r.file, Err: err}
+	}
+	return n, err
+}
 
-	reader := csv.NewReader(file)
+// For holding the results of a comparison.
+type Result struct {
+	Rows1      [][]csvcheck.StringHashable // The result rows of the first csv, with the columns row.
+	Rows2      [][]csvcheck.StringHashable // The result rows of the second csv, with the columns row.
+	Partial    bool                        // Whether the comparison was cancelled and only covers the rows read until then.
+	Stats      *DifferenceStats            // The difference statistics for the stats function, which has no result rows.
+	SchemaDiff *SchemaDiff                 // The schema differences for the schemadiff function, which has no result rows.
+	// The reconciliation for the reconcile function, which has no result rows.
+	Reconciliation *Reconciliation
+}
 
-	records, err := reader.ReadAll()
+// Compares the csv data read from src1 and src2 based off of the config. Unlike
+// GetResArrays, it does not depend on any command-line state.
+//
+// If the context is cancelled while the data is being read, the rows read so far
+// are compared and returned as a partial result together with the context's error.
+// If it is cancelled later on, only the error is returned. Errors reading the
+// sources are returned as a *ReadError.
+func Compare(ctx context.Context, src1, src2 io.Reader, cfg Config) (*Result, error) {
+	err := cfg.Validate()
 	if err != nil {
-		log.Panic(err)
+		return nil, err
 	}
 
-	res := make([][]csvcheck.StringHashable, len(records))
-	for i, record := range records {
-		res[i] = csvcheck.GetRowFromRow(record)
+	tracker := newProgressTracker(cfg.ProgressReporter, src1, src2)
+	src1, src2 = sourceReader{src1, 1}, sourceReader{src2, 2}
+
+	if cfg.Encoding1 != "" {
+		src1 = tracker.countRawBytes(1, src1)
+	}
+	if cfg.Encoding2 != "" {
+		src2 = tracker.countRawBytes(2, src2)
+	}
+	src1, err = newDecodingReader(src1, cfg.Encoding1, 1)
+	if err != nil {
+		return nil, err
+	}
+	src2, err = newDecodingReader(src2, cfg.Encoding2, 2)
+	if err != nil {
+		return nil, err
 	}
 
-	return res
+	if cfg.Function == FunctionStringReconcile {
+		reconciliation, err := reconcileCsv(ctx, csv.NewReader(src1), csv.NewReader(src2), cfg, tracker)
+		if err != nil && (reconciliation == nil || ctx.Err() == nil) {
+			return nil, err
+		}
+		return &Result{Partial: err != nil, Reconciliation: reconciliation}, err
+	}
+
+	var res1, res2 [][]csvcheck.StringHashable
+	if cfg.Method == MethodStringSorted {
+		res1, res2, err = getResArraysSorted(ctx, csv.NewReader(src1), csv.NewReader(src2), cfg, tracker)
+		if err != nil && (res1 == nil || ctx.Err() == nil) {
+			return nil, err
+		}
+	} else {
+		var csvArray1, csvArray2 [][]csvcheck.StringHashable
+		var err1, err2 error
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			csvArray1, err1 = readCsvWithContext(ctx, src1, 1, tracker)
+		}()
+		go func() {
+			defer wg.Done()
+			csvArray2, err2 = readCsvWithContext(ctx, src2, 2, tracker)
+		}()
+		wg.Wait()
+		if ctx.Err() == nil {
+			if err = errors.Join(err1, err2); err != nil {
+				return nil, err
+			}
+		}
+
+		if cfg.Function == FunctionStringStats {
+			stats, err := GetDifferenceStats(csvArray1, csvArray2, cfg)
+			if err != nil {
+				return nil, err
+			}
+			err = ctx.Err()
+			return &Result{Partial: err != nil, Stats: stats}, err
+		}
+		if cfg.Function == FunctionStringSchemaDiff {
+			schemaDiff, err := GetSchemaDiff(csvArray1, csvArray2, cfg)
+			if err != nil {
+				return nil, err
+			}
+			err = ctx.Err()
+			return &Result{Partial: err != nil, SchemaDiff: schemaDiff}, err
+		}
+
+		compareCtx := ctx
+		if ctx.Err() != nil {
+			compareCtx = context.WithoutCancel(ctx)
+		}
+		res1, res2, err = getResArrays(compareCtx, csvArray1, csvArray2, cfg, tracker)
+		if err != nil {
+			return nil, err
+		}
+		err = ctx.Err()
+	}
+
+	return &Result{Rows1: res1, Rows2: res2, Partial: err != nil}, err
 }
 
-func WriteString(filePath string, content string) {
-	file, err := os.Create(filePath)
+// Reads the records of the csv data until the end or until the context is
+// cancelled, in which case the records read so far are returned together with
+// the context's error. Progress is reported as the given file.
+func readCsvWithContext(ctx context.Context, src io.Reader, file int, tracker *progressTracker) ([][]csvcheck.StringHashable, error) {
+	reader := csv.NewReader(src)
+
+	res := [][]csvcheck.StringHashable{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, csvcheck.GetRowFromRow(record))
+		if len(res)%progressInterval == 0 {
+			tracker.reportReading(file, int64(len(res)), reader.InputOffset())
+			if err := ctx.Err(); err != nil {
+				return res, err
+			}
+		}
+	}
+	tracker.reportReading(file, int64(len(res)), reader.InputOffset())
+
+	return res, nil
+}
+
+// Reads all the records of the csv data.
+func ReadCsv(src io.Reader) ([][]csvcheck.StringHashable, error) {
+	return readCsvWithContext(context.Background(), src, 0, nil)
+}
+
+// Returns the csv array formatted as csv data that ReadCsv reads back as it is. Unlike
+// csvcheck.StringFormatCsvArray, values containing commas, quotes or newlines are quoted.
+func FormatCsvArray(arr [][]csvcheck.StringHashable) (string, error) {
+	var builder strings.Builder
+	writer := csv.NewWriter(&builder)
+	for _, row := range arr {
+		err := writer.Write(getRowStrings(row))
+		if err != nil {
+			return "", err
+		}
+	}
+	writer.Flush()
+	return builder.String(), writer.Error()
+}
+
+func ReadCsvFile(filePath string) [][]csvcheck.StringHashable {
+	file, err := os.Open(filePath)
 	if err != nil {
-		panic(err)
+		log.Panic(err)
 	}
 	defer file.Close()
 
-	_, err = file.WriteString(content)
+	res, err := ReadCsv(file)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return res
+}
+
+// Writes the content to the file, replacing it if it exists. See WriteFile.
+func WriteString(filePath string, content string) {
+	err := WriteFile(filePath, content, WriteOptions{Overwrite: true})
 	if err != nil {
 		panic(err)
 	}
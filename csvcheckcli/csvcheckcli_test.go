@@ -1,11 +1,15 @@
 package csvcheckcli_test
 
 import (
+	"context"
 	"csvcheckcli/csvcheckcli"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/BrianWeiHaoMa/csvcheck"
 	"github.com/stretchr/testify/assert"
@@ -44,6 +48,17 @@ type userInputSolid struct {
 	ColumnsToDelete     []string
 	ColumnsArrangement1 []string
 	ColumnsArrangement2 []string
+	normalizeHeaders    bool
+	noHeader1           bool
+	noHeader2           bool
+	limit               int
+	head                bool
+	tail                bool
+	sample              int
+	seed                int64
+	workers             int
+	emitHash            bool
+	arrangeMode         string
 }
 
 func (o userInputSolid) getUserInput() csvcheckcli.UserInput {
@@ -63,6 +78,17 @@ func (o userInputSolid) getUserInput() csvcheckcli.UserInput {
 		ColumnsToDelete:     &o.ColumnsToDelete,
 		ColumnsArrangement1: &o.ColumnsArrangement1,
 		ColumnsArrangement2: &o.ColumnsArrangement2,
+		NormalizeHeaders:    &o.normalizeHeaders,
+		NoHeader1:           &o.noHeader1,
+		NoHeader2:           &o.noHeader2,
+		Limit:               &o.limit,
+		Head:                &o.head,
+		Tail:                &o.tail,
+		Sample:              &o.sample,
+		Seed:                &o.seed,
+		Workers:             &o.workers,
+		EmitHash:            &o.emitHash,
+		ArrangeMode:         &o.arrangeMode,
 	}
 }
 
@@ -824,6 +850,77 @@ z,7,z
 	assert.NotNil(t, err)
 }
 
+func TestGetResArraysDifferentMatchRearrangeWithArrangeModes(t *testing.T) {
+	arr1 := Get2DArrayFromCsvString(`
+a,b,c
+1,2,3
+4,5,6
+`)
+	arr2 := Get2DArrayFromCsvString(`
+a,c,d
+1,3,x
+7,8,y
+`)
+
+	for i, data := range []struct {
+		arrangeMode         string
+		ColumnsArrangement1 []string
+		ColumnsArrangement2 []string
+		expected1           string
+		expected2           string
+		expectError         bool
+	}{
+		{
+			arrangeMode:         csvcheckcli.ArrangeModeFront,
+			ColumnsArrangement1: []string{csvcheckcli.IndexColumnName},
+			ColumnsArrangement2: []string{"d", csvcheckcli.IndexColumnName},
+			expected1:           "_ind,a,b,c\n2,4,5,6\n",
+			expected2:           "d,_ind,a,c\ny,2,7,8\n",
+		},
+		{
+			arrangeMode:         csvcheckcli.ArrangeModeBack,
+			ColumnsArrangement1: []string{"a"},
+			ColumnsArrangement2: []string{"_*", "a"},
+			expected1:           "b,c,_ind,a\n5,6,2,4\n",
+			expected2:           "c,d,_ind,a\n8,y,2,7\n",
+		},
+		{
+			arrangeMode:         csvcheckcli.ArrangeModeStrict,
+			ColumnsArrangement1: []string{csvcheckcli.IndexColumnName, "c", "b", "a"},
+			ColumnsArrangement2: []string{csvcheckcli.IndexColumnName},
+			expectError:         true,
+		},
+		{
+			arrangeMode:         csvcheckcli.ArrangeModeFront,
+			ColumnsArrangement1: []string{"z"},
+			expectError:         true,
+		},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		input := userInputSolid{
+			inputDir:            "/path/to/input/dir",
+			files:               []string{"file1.csv", "file2.csv"},
+			method:              csvcheckcli.MethodStringMatch,
+			function:            csvcheckcli.FunctionStringDifferent,
+			keepIndex:           true,
+			columnsToUse:        []string{"a"},
+			ColumnsArrangement1: data.ColumnsArrangement1,
+			ColumnsArrangement2: data.ColumnsArrangement2,
+			arrangeMode:         data.arrangeMode,
+		}.getUserInput()
+
+		res1, res2, err := csvcheckcli.GetResArrays(arr1, arr2, input)
+
+		if data.expectError {
+			assert.NotNil(t, err, indexString)
+			continue
+		}
+		assert.Nil(t, err, indexString)
+		assert.Equal(t, Get2DArrayFromCsvString(data.expected1), res1, indexString)
+		assert.Equal(t, Get2DArrayFromCsvString(data.expected2), res2, indexString)
+	}
+}
+
 func TestGetResArraysDifferentMatchUseCommonColumnsAutoAlignDeleteCommonAndDifferentColumnsAndRearrange(t *testing.T) {
 	input := userInputSolid{
 		inputDir:            "/path/to/input/dir",
@@ -873,3 +970,26 @@ z,z
 	assert.Equal(t, expected1, res1)
 	assert.Equal(t, expected2, res2)
 }
+
+func TestCompareReadError(t *testing.T) {
+	failure := errors.New("disk failure")
+	for i, method := range []string{csvcheckcli.MethodStringSet, csvcheckcli.MethodStringSorted} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		cfg := csvcheckcli.NewConfig(
+			csvcheckcli.WithMethod(method),
+			csvcheckcli.WithFunction(csvcheckcli.FunctionStringCommon),
+		)
+
+		src2 := io.MultiReader(strings.NewReader("a\n1\n"), iotest.ErrReader(failure))
+		_, err := csvcheckcli.Compare(context.Background(), strings.NewReader("a\n1\n"), src2, cfg)
+
+		var readError *csvcheckcli.ReadError
+		assert.ErrorAs(t, err, &readError, indexString)
+		assert.Equal(t, 2, readError.File, indexString)
+		assert.ErrorIs(t, err, failure, indexString)
+
+		_, err = csvcheckcli.Compare(context.Background(), strings.NewReader("a\n1\n"), strings.NewReader("a\n\"1\n"), cfg)
+		assert.NotNil(t, err, indexString)
+		assert.False(t, errors.As(err, &readError), indexString)
+	}
+}
@@ -0,0 +1,189 @@
+package csvcheckcli
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+)
+
+// Returns the csv array with only the first (or last if fromTail is true) limit rows
+// below the header, and the number of rows left out. A limit of 0 or less keeps all rows.
+func LimitCsvArray(arr [][]csvcheck.StringHashable, limit int, fromTail bool) ([][]csvcheck.StringHashable, int) {
+	if len(arr) == 0 || limit <= 0 || len(arr)-1 <= limit {
+		return arr, 0
+	}
+
+	res := make([][]csvcheck.StringHashable, 0, limit+1)
+	res = append(res, arr[0])
+	if fromTail {
+		res = append(res, arr[len(arr)-limit:]...)
+	} else {
+		res = append(res, arr[1:limit+1]...)
+	}
+	return res, len(arr) - 1 - limit
+}
+
+// Returns the csv array with a random sample of size rows below the header, kept in
+// their original order, and the number of rows left out. The same seed always gives
+// the same sample. A size of 0 or less keeps all rows.
+func SampleCsvArray(arr [][]csvcheck.StringHashable, size int, seed int64) ([][]csvcheck.StringHashable, int) {
+	rowCount := len(arr) - 1
+	if len(arr) == 0 || size <= 0 || rowCount <= size {
+		return arr, 0
+	}
+
+	random := rand.New(rand.NewSource(seed))
+	chosen := make([]int, size)
+	for i := range chosen {
+		chosen[i] = i
+	}
+	for i := size; i < rowCount; i++ {
+		j := random.Intn(i + 1)
+		if j < size {
+			chosen[j] = i
+		}
+	}
+	sort.Ints(chosen)
+
+	res := make([][]csvcheck.StringHashable, 0, size+1)
+	res = append(res, arr[0])
+	for _, i := range chosen {
+		res = append(res, arr[i+1])
+	}
+	return res, rowCount - size
+}
+
+// Returns the part of the result array to display based off of the config
+// and the number of rows left out.
+func GetDisplayArray(arr [][]csvcheck.StringHashable, cfg Config) ([][]csvcheck.StringHashable, int) {
+	if cfg.Sample > 0 {
+		return SampleCsvArray(arr, cfg.Sample, cfg.Seed)
+	}
+	return LimitCsvArray(arr, cfg.Limit, cfg.Tail)
+}
+
+// Returns n with commas separating the thousands.
+func formatThousands(n int) string {
+	s := fmt.Sprintf("%d", n)
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return sign + s
+}
+
+// Returns the footer noting how many rows were left out of the display.
+func FormatOmittedRowsFooter(omitted int) string {
+	if omitted == 1 {
+		return "... and 1 more row\n"
+	}
+	return fmt.Sprintf("... and %s more rows\n", formatThousands(omitted))
+}
+
+// Returns the csv array formatted as a json array with an object per row below the
+// header, keyed by the columns in their original order. Every row goes on a line of its own.
+func JsonFormatCsvArray(arr [][]csvcheck.StringHashable) (string, error) {
+	err := csvcheck.CheckForProperCsvArray(arr)
+	if err != nil {
+		return "", err
+	}
+	if len(arr) <= 1 {
+		return "[]\n", nil
+	}
+
+	keys := make([]string, len(arr[0]))
+	for i, column := range arr[0] {
+		key, _ := json.Marshal(column.StringHash())
+		keys[i] = string(key)
+	}
+
+	var builder strings.Builder
+	builder.WriteString("[\n")
+	for i, row := range arr[1:] {
+		builder.WriteString("  {")
+		for j, cell := range row {
+			if j > 0 {
+				builder.WriteByte(',')
+			}
+			value, _ := json.Marshal(cell.StringHash())
+			builder.WriteString(keys[j])
+			builder.WriteByte(':')
+			builder.Write(value)
+		}
+		builder.WriteByte('}')
+		if i < len(arr)-2 {
+			builder.WriteByte(',')
+		}
+		builder.WriteByte('\n')
+	}
+	builder.WriteString("]\n")
+	return builder.String(), nil
+}
+
+// Returns the cell escaped for a markdown table. Pipes are escaped and line breaks
+// are replaced by <br>. Cells longer than maxLength runes are truncated first, and
+// negative values of maxLength mean no limit.
+func escapeMarkdownCell(s string, maxLength int) string {
+	if maxLength >= 0 && utf8.RuneCountInString(s) > maxLength {
+		s = string([]rune(s)[:maxLength]) + csvcheck.TruncatedMark
+	}
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\r\n", "<br>")
+	s = strings.ReplaceAll(s, "\n", "<br>")
+	return s
+}
+
+// Returns the csv array formatted as a github-flavoured markdown table. Cells
+// longer than maxLength runes are truncated, and negative values mean no limit.
+func MarkdownFormatCsvArray(arr [][]csvcheck.StringHashable, maxLength int) (string, error) {
+	err := csvcheck.CheckForProperCsvArray(arr)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	writeRow := func(cells []string) {
+		builder.WriteString("| ")
+		builder.WriteString(strings.Join(cells, " | "))
+		builder.WriteString(" |\n")
+	}
+
+	for i, row := range arr {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			cells[j] = escapeMarkdownCell(cell.StringHash(), maxLength)
+		}
+		writeRow(cells)
+
+		if i == 0 {
+			separators := make([]string, len(row))
+			for j := range separators {
+				separators[j] = "---"
+			}
+			writeRow(separators)
+		}
+	}
+	return builder.String(), nil
+}
+
+// Returns a markdown block summarizing the comparison, for pasting into
+// comments. The row counts do not include the columns rows.
+func FormatMarkdownSummary(fileName1, fileName2 string, rows1, rows2 int, cfg Config) string {
+	summary := [][]csvcheck.StringHashable{
+		csvcheck.GetRowFromRow([]string{"File", "Result rows"}),
+		csvcheck.GetRowFromRow([]string{fileName1, formatThousands(rows1)}),
+		csvcheck.GetRowFromRow([]string{fileName2, formatThousands(rows2)}),
+	}
+	table, _ := MarkdownFormatCsvArray(summary, -1)
+	return fmt.Sprintf("#### csvcheck summary\n%s\nFunction `%s`, method `%s`.\n", table, cfg.Function, cfg.Method)
+}
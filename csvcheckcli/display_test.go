@@ -0,0 +1,143 @@
+package csvcheckcli_test
+
+import (
+	"csvcheckcli/csvcheckcli"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitCsvArray(t *testing.T) {
+	arr := Get2DArrayFromCsvString(`
+a,b
+1,1
+2,2
+3,3
+4,4
+`)
+
+	head, omitted := csvcheckcli.LimitCsvArray(arr, 2, false)
+	assert.Equal(t, Get2DArrayFromCsvString("a,b\n1,1\n2,2\n"), head)
+	assert.Equal(t, 2, omitted)
+
+	tail, omitted := csvcheckcli.LimitCsvArray(arr, 3, true)
+	assert.Equal(t, Get2DArrayFromCsvString("a,b\n2,2\n3,3\n4,4\n"), tail)
+	assert.Equal(t, 1, omitted)
+
+	all, omitted := csvcheckcli.LimitCsvArray(arr, 0, false)
+	assert.Equal(t, arr, all)
+	assert.Equal(t, 0, omitted)
+}
+
+func TestSampleCsvArray(t *testing.T) {
+	arr := Get2DArrayFromCsvString(`
+a
+1
+2
+3
+4
+5
+6
+7
+8
+`)
+
+	sample1, omitted := csvcheckcli.SampleCsvArray(arr, 3, 42)
+	sample2, _ := csvcheckcli.SampleCsvArray(arr, 3, 42)
+
+	assert.Equal(t, 5, omitted)
+	assert.Equal(t, 4, len(sample1))
+	assert.Equal(t, arr[0], sample1[0])
+	assert.Equal(t, sample1, sample2)
+	for i := 2; i < len(sample1); i++ {
+		assert.Less(t, sample1[i-1][0].StringHash(), sample1[i][0].StringHash())
+	}
+}
+
+func TestFormatOmittedRowsFooter(t *testing.T) {
+	for i, data := range []struct {
+		omitted  int
+		expected string
+	}{
+		{omitted: 1, expected: "... and 1 more row\n"},
+		{omitted: 999, expected: "... and 999 more rows\n"},
+		{omitted: 1999990, expected: "... and 1,999,990 more rows\n"},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		assert.Equal(t, data.expected, csvcheckcli.FormatOmittedRowsFooter(data.omitted), indexString)
+	}
+}
+
+func TestParseUserInputDisplayOptions(t *testing.T) {
+	for i, data := range []struct {
+		input       userInputSolid
+		expectError bool
+	}{
+		{input: userInputSolid{limit: 10, tail: true}, expectError: false},
+		{input: userInputSolid{sample: 10, seed: 3}, expectError: false},
+		{input: userInputSolid{limit: 10, sample: 10}, expectError: true},
+		{input: userInputSolid{limit: 10, head: true, tail: true}, expectError: true},
+		{input: userInputSolid{tail: true}, expectError: true},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		data.input.inputDir = "/path/to/input/dir"
+		data.input.files = []string{"file1.csv", "file2.csv"}
+		data.input.method = csvcheckcli.MethodStringSet
+		data.input.function = csvcheckcli.FunctionStringDifferent
+		input := data.input.getUserInput()
+		_, err := csvcheckcli.ParseUserInput(&input)
+		if data.expectError {
+			assert.NotNil(t, err, indexString)
+		} else {
+			assert.Nil(t, err, indexString)
+		}
+	}
+}
+
+func TestJsonFormatCsvArray(t *testing.T) {
+	for i, data := range []struct {
+		csvString string
+		expected  string
+	}{
+		{csvString: "b,a\n1,\"x \"\"y\"\"\"\n2,z\n", expected: "[\n  {\"b\":\"1\",\"a\":\"x \\\"y\\\"\"},\n  {\"b\":\"2\",\"a\":\"z\"}\n]\n"},
+		{csvString: "b,a\n", expected: "[]\n"},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		res, err := csvcheckcli.JsonFormatCsvArray(Get2DArrayFromCsvString(data.csvString))
+		assert.Nil(t, err, indexString)
+		assert.Equal(t, data.expected, res, indexString)
+	}
+}
+
+func TestMarkdownFormatCsvArray(t *testing.T) {
+	for i, data := range []struct {
+		csvString string
+		maxLength int
+		expected  string
+	}{
+		{csvString: "a,b\n1,x|y\n", maxLength: -1, expected: "| a | b |\n| --- | --- |\n| 1 | x\\|y |\n"},
+		{csvString: "a,b\n1,\"x\ny\"\n", maxLength: -1, expected: "| a | b |\n| --- | --- |\n| 1 | x<br>y |\n"},
+		{csvString: "a,b\n1,éééé|\n", maxLength: 2, expected: "| a | b |\n| --- | --- |\n| 1 | éé.. |\n"},
+		{csvString: "a,b\n", maxLength: 2, expected: "| a | b |\n| --- | --- |\n"},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		res, err := csvcheckcli.MarkdownFormatCsvArray(Get2DArrayFromCsvString(data.csvString), data.maxLength)
+		assert.Nil(t, err, indexString)
+		assert.Equal(t, data.expected, res, indexString)
+	}
+}
+
+func TestFormatMarkdownSummary(t *testing.T) {
+	cfg := csvcheckcli.NewConfig(csvcheckcli.WithFunction(csvcheckcli.FunctionStringDifferent))
+
+	expected := `#### csvcheck summary
+| File | Result rows |
+| --- | --- |
+| a.csv | 1,500 |
+| b.csv | 0 |
+
+Function ` + "`different`, method `set`" + `.
+`
+	assert.Equal(t, expected, csvcheckcli.FormatMarkdownSummary("a.csv", "b.csv", 1500, 0, cfg))
+}
@@ -0,0 +1,197 @@
+package csvcheckcli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// The encodings detected by the auto encoding.
+const (
+	EncodingAuto        = "auto"
+	EncodingUTF8        = "utf-8"
+	EncodingUTF16LE     = "utf-16le"
+	EncodingUTF16BE     = "utf-16be"
+	EncodingWindows1252 = "windows-1252"
+)
+
+// The number of bytes at the start of a file the auto encoding looks at.
+const encodingDetectionSize = 64 << 10
+
+// Returns the encoding with the IANA name or alias, such as utf-16le, windows-1252
+// or iso-8859-1, ignoring case.
+func getEncoding(name string) (encoding.Encoding, error) {
+	enc, err := ianaindex.IANA.Encoding(strings.ToLower(strings.TrimSpace(name)))
+	if err != nil || enc == nil {
+		return nil, fmt.Errorf("unsupported encoding %s", name)
+	}
+	return enc, nil
+}
+
+// Checks that the encoding is supported. auto is only allowed if allowAuto is true.
+func checkEncoding(option, name string, allowAuto bool) error {
+	if name == "" || (allowAuto && name == EncodingAuto) {
+		return nil
+	}
+	_, err := getEncoding(name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", option, err)
+	}
+	return nil
+}
+
+// Returns the encoding of the start of a file. Byte order marks are looked for first.
+// Without one, text with zero bytes in every other position is taken as UTF-16, valid
+// UTF-8 as UTF-8 and anything else as Windows-1252, which every byte is valid in.
+func DetectEncoding(sample []byte) string {
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xef, 0xbb, 0xbf}):
+		return EncodingUTF8
+	case bytes.HasPrefix(sample, []byte{0xff, 0xfe}):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(sample, []byte{0xfe, 0xff}):
+		return EncodingUTF16BE
+	}
+
+	zeros := [2]int{}
+	for i, b := range sample {
+		if b == 0 {
+			zeros[i%2]++
+		}
+	}
+	pairs := len(sample) / 2
+	switch {
+	case pairs > 0 && zeros[1] > pairs/2 && zeros[0] < pairs/10:
+		return EncodingUTF16LE
+	case pairs > 0 && zeros[0] > pairs/2 && zeros[1] < pairs/10:
+		return EncodingUTF16BE
+	}
+
+	// The sample may end in the middle of a character.
+	if utf8.Valid(sample[:getCompleteRunesLength(sample)]) {
+		return EncodingUTF8
+	}
+	return EncodingWindows1252
+}
+
+// Returns the length of the data without the bytes of a character it ends in the middle of.
+func getCompleteRunesLength(data []byte) int {
+	for k := 1; k <= min(utf8.UTFMax-1, len(data)); k++ {
+		if utf8.RuneStart(data[len(data)-k]) {
+			if !utf8.FullRune(data[len(data)-k:]) {
+				return len(data) - k
+			}
+			break
+		}
+	}
+	return len(data)
+}
+
+// Returned when reading data that the auto encoding detected as UTF-8 from its
+// start, but that is not valid UTF-8 further on.
+type InvalidUTF8Error struct {
+	Offset int64 // The offset of the first invalid byte.
+	File   int   // 1 or 2 for the first or second file of a comparison, or 0 if unknown.
+}
+
+func (e *InvalidUTF8Error) Error() string {
+	hint := fmt.Sprintf("give the encoding of the data, such as %s", EncodingWindows1252)
+	if e.File > 0 {
+		hint = fmt.Sprintf("use --encoding%d %s or the encoding of the file", e.File, EncodingWindows1252)
+	}
+	return fmt.Sprintf("invalid UTF-8 at byte %d, although the start of the data was detected as UTF-8, %s", e.Offset, hint)
+}
+
+// A reader returning an InvalidUTF8Error on the first invalid UTF-8 sequence of the
+// data of the underlying reader, rather than letting it be replaced when decoding.
+type utf8ValidatingReader struct {
+	reader  io.Reader
+	file    int
+	offset  int64  // The offset of the data not yet validated.
+	pending []byte // The start of a character split across reads.
+}
+
+func (r *utf8ValidatingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err != nil && err != io.EOF {
+		return n, err
+	}
+
+	data := p[:n]
+	if len(r.pending) > 0 {
+		data = append(r.pending, data...)
+	}
+	complete := len(data)
+	if err == nil {
+		complete = getCompleteRunesLength(data)
+	}
+	for i := 0; i < complete; {
+		c, size := utf8.DecodeRune(data[i:complete])
+		if c == utf8.RuneError && size <= 1 {
+			return 0, &InvalidUTF8Error{Offset: r.offset + int64(i), File: r.file}
+		}
+		i += size
+	}
+	r.offset += int64(complete)
+	r.pending = append(r.pending[:0], data[complete:]...)
+	return n, err
+}
+
+// Returns a reader of the data of src transcoded from the encoding to UTF-8. The auto
+// encoding is detected with DetectEncoding. As only the start of the data is looked at,
+// data detected as UTF-8 is still checked as it is read, and an *InvalidUTF8Error is
+// returned if it is not. Byte order marks are removed. If the encoding is empty, src is
+// returned as it is.
+func NewDecodingReader(src io.Reader, name string) (io.Reader, error) {
+	return newDecodingReader(src, name, 0)
+}
+
+// Returns a reader like NewDecodingReader for the file of a comparison.
+func newDecodingReader(src io.Reader, name string, file int) (io.Reader, error) {
+	if name == "" {
+		return src, nil
+	}
+	if name == EncodingAuto {
+		reader := bufio.NewReaderSize(src, encodingDetectionSize)
+		sample, err := reader.Peek(encodingDetectionSize)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return nil, err
+		}
+		src, name = reader, DetectEncoding(sample)
+		if name == EncodingUTF8 {
+			src = &utf8ValidatingReader{reader: src, file: file}
+		}
+	}
+
+	enc, err := getEncoding(name)
+	if err != nil {
+		return nil, err
+	}
+	return transform.NewReader(src, unicode.BOMOverride(enc.NewDecoder())), nil
+}
+
+// Returns the UTF-8 text encoded in the encoding. An error is returned if the text has
+// characters the encoding cannot represent. If the encoding is empty, the text is
+// returned as it is.
+func EncodeString(s string, name string) (string, error) {
+	if name == "" {
+		return s, nil
+	}
+	enc, err := getEncoding(name)
+	if err != nil {
+		return "", err
+	}
+	res, err := enc.NewEncoder().String(s)
+	if err != nil {
+		return "", fmt.Errorf("cannot encode the output in %s: %w", name, err)
+	}
+	return res, nil
+}
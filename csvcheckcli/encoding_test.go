@@ -0,0 +1,153 @@
+package csvcheckcli_test
+
+import (
+	"context"
+	"csvcheckcli/csvcheckcli"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the text as UTF-16, little endian if littleEndian is true, without a byte order mark.
+func encodeUTF16(s string, littleEndian bool) []byte {
+	res := []byte{}
+	for _, r := range s {
+		if littleEndian {
+			res = append(res, byte(r), byte(r>>8))
+		} else {
+			res = append(res, byte(r>>8), byte(r))
+		}
+	}
+	return res
+}
+
+func TestDetectEncoding(t *testing.T) {
+	for i, data := range []struct {
+		sample   []byte
+		expected string
+	}{
+		{sample: []byte("a,b\n1,2\n"), expected: csvcheckcli.EncodingUTF8},
+		{sample: []byte("\xef\xbb\xbfa,b\n"), expected: csvcheckcli.EncodingUTF8},
+		{sample: []byte("name\nJosé\n"), expected: csvcheckcli.EncodingUTF8},
+		{sample: []byte("name\nJos\xc3"), expected: csvcheckcli.EncodingUTF8},
+		{sample: []byte("name\nJos\xe9\n"), expected: csvcheckcli.EncodingWindows1252},
+		{sample: append([]byte{0xff, 0xfe}, encodeUTF16("a,b\n", true)...), expected: csvcheckcli.EncodingUTF16LE},
+		{sample: append([]byte{0xfe, 0xff}, encodeUTF16("a,b\n", false)...), expected: csvcheckcli.EncodingUTF16BE},
+		{sample: encodeUTF16("name\nJosé\n", true), expected: csvcheckcli.EncodingUTF16LE},
+		{sample: encodeUTF16("name\nJosé\n", false), expected: csvcheckcli.EncodingUTF16BE},
+		{sample: []byte{}, expected: csvcheckcli.EncodingUTF8},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		assert.Equal(t, data.expected, csvcheckcli.DetectEncoding(data.sample), indexString)
+	}
+}
+
+func TestNewDecodingReader(t *testing.T) {
+	for i, data := range []struct {
+		input    []byte
+		encoding string
+		expected string
+	}{
+		{input: []byte("\xef\xbb\xbfname\n"), encoding: "", expected: "\ufeffname\n"},
+		{input: []byte("\xef\xbb\xbfname\n"), encoding: csvcheckcli.EncodingUTF8, expected: "name\n"},
+		{input: []byte("name\nJos\xe9\n"), encoding: csvcheckcli.EncodingWindows1252, expected: "name\nJosé\n"},
+		{input: []byte("name\nJos\xe9\n"), encoding: "ISO-8859-1", expected: "name\nJosé\n"},
+		{input: []byte("name\nJos\xe9\n"), encoding: csvcheckcli.EncodingAuto, expected: "name\nJosé\n"},
+		{input: []byte("name\nJosé\n"), encoding: csvcheckcli.EncodingAuto, expected: "name\nJosé\n"},
+		{input: append([]byte{0xff, 0xfe}, encodeUTF16("name\nJosé\n", true)...), encoding: csvcheckcli.EncodingAuto, expected: "name\nJosé\n"},
+		{input: append([]byte{0xff, 0xfe}, encodeUTF16("name\nJosé\n", true)...), encoding: csvcheckcli.EncodingUTF16LE, expected: "name\nJosé\n"},
+		{input: encodeUTF16("name\nJosé\n", false), encoding: csvcheckcli.EncodingAuto, expected: "name\nJosé\n"},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		reader, err := csvcheckcli.NewDecodingReader(strings.NewReader(string(data.input)), data.encoding)
+		assert.Nil(t, err, indexString)
+		res, err := io.ReadAll(reader)
+		assert.Nil(t, err, indexString)
+		assert.Equal(t, data.expected, string(res), indexString)
+	}
+
+	_, err := csvcheckcli.NewDecodingReader(strings.NewReader(""), "nosuchencoding")
+	assert.NotNil(t, err)
+}
+
+func TestNewDecodingReaderInvalidUTF8AfterDetection(t *testing.T) {
+	// The start of the data is longer than what the auto encoding looks at.
+	prefix := "name,n\n" + strings.Repeat("Anna,1\n", 10000)
+
+	reader, err := csvcheckcli.NewDecodingReader(strings.NewReader(prefix+"Jos\xe9,2\n"), csvcheckcli.EncodingAuto)
+	assert.Nil(t, err)
+	_, err = io.ReadAll(reader)
+	var invalidUTF8 *csvcheckcli.InvalidUTF8Error
+	assert.ErrorAs(t, err, &invalidUTF8)
+	assert.Equal(t, int64(len(prefix)+3), invalidUTF8.Offset)
+
+	reader, err = csvcheckcli.NewDecodingReader(strings.NewReader(prefix+"Jos\xc3"), csvcheckcli.EncodingAuto)
+	assert.Nil(t, err)
+	_, err = io.ReadAll(reader)
+	assert.ErrorAs(t, err, &invalidUTF8)
+
+	reader, err = csvcheckcli.NewDecodingReader(iotest.OneByteReader(strings.NewReader(prefix+"José,2\n\uFFFD,3\n")), csvcheckcli.EncodingAuto)
+	assert.Nil(t, err)
+	res, err := io.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, prefix+"José,2\n\uFFFD,3\n", string(res))
+
+	cfg := csvcheckcli.NewConfig(csvcheckcli.WithFunction(csvcheckcli.FunctionStringCommon))
+	cfg.Encoding2 = csvcheckcli.EncodingAuto
+	_, err = csvcheckcli.Compare(context.Background(), strings.NewReader("name,n\n"), strings.NewReader(prefix+"Jos\xe9,2\n"), cfg)
+	assert.ErrorAs(t, err, &invalidUTF8)
+	assert.Contains(t, err.Error(), "--encoding2 windows-1252")
+}
+
+func TestEncodeString(t *testing.T) {
+	res, err := csvcheckcli.EncodeString("name\nJosé\n", csvcheckcli.EncodingWindows1252)
+	assert.Nil(t, err)
+	assert.Equal(t, "name\nJos\xe9\n", res)
+
+	res, err = csvcheckcli.EncodeString("name\nJosé\n", csvcheckcli.EncodingUTF16BE)
+	assert.Nil(t, err)
+	assert.Equal(t, string(encodeUTF16("name\nJosé\n", false)), res)
+
+	res, err = csvcheckcli.EncodeString("name\nJosé\n", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "name\nJosé\n", res)
+
+	_, err = csvcheckcli.EncodeString("name\n日本\n", csvcheckcli.EncodingWindows1252)
+	assert.NotNil(t, err)
+}
+
+func TestCompareEncodings(t *testing.T) {
+	src1 := strings.NewReader("name,city\nJos\xe9,M\xfcnchen\nAnna,Berlin\n")
+	src2 := strings.NewReader(string(append([]byte{0xff, 0xfe}, encodeUTF16("name,city\nJosé,München\nBob,Paris\n", true)...)))
+	cfg := csvcheckcli.NewConfig(csvcheckcli.WithFunction(csvcheckcli.FunctionStringCommon))
+	cfg.Encoding1 = csvcheckcli.EncodingWindows1252
+	cfg.Encoding2 = csvcheckcli.EncodingAuto
+
+	result, err := csvcheckcli.Compare(context.Background(), src1, src2, cfg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, Get2DArrayFromCsvString("name,city\nJosé,München\n"), result.Rows1)
+	assert.Equal(t, Get2DArrayFromCsvString("name,city\nJosé,München\n"), result.Rows2)
+}
+
+func TestWriteFileEncoding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.csv")
+
+	err := csvcheckcli.WriteFile(path, "name\nJosé\n", csvcheckcli.WriteOptions{Encoding: csvcheckcli.EncodingWindows1252})
+	assert.Nil(t, err)
+	content, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "name\nJos\xe9\n", string(content))
+
+	err = csvcheckcli.WriteFile(path, "name\n日本\n", csvcheckcli.WriteOptions{Overwrite: true, Encoding: csvcheckcli.EncodingWindows1252})
+	assert.NotNil(t, err)
+	content, err = os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "name\nJos\xe9\n", string(content))
+}
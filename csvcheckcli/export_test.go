@@ -0,0 +1,8 @@
+package csvcheckcli
+
+// Makes WriteFile link files with the function until the returned function is called.
+func SetLinkFile(link func(oldname, newname string) error) (restore func()) {
+	previous := linkFile
+	linkFile = link
+	return func() { linkFile = previous }
+}
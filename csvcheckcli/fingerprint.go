@@ -0,0 +1,332 @@
+package csvcheckcli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+	"github.com/cespare/xxhash"
+	"github.com/spf13/pflag"
+)
+
+const HashColumnName = "_hash"
+
+// For holding the options of the fingerprint command.
+type FingerprintConfig struct {
+	InputDir         string
+	File             string
+	KeyColumns       []string
+	ColumnsToUse     []string
+	ColumnsToIgnore  []string
+	NormalizeHeaders bool
+	NoHeader         bool
+	OutputDir        string
+	AddTimestamp     bool
+	Against          string
+	Overwrite        bool
+	NoClobber        bool
+	FileMode         os.FileMode
+}
+
+// For holding the rows that changed between two fingerprint arrays. Every array
+// has the key columns and the hash column, with the columns row.
+type FingerprintDiff struct {
+	New     [][]csvcheck.StringHashable // Rows whose key is only in the new fingerprints.
+	Removed [][]csvcheck.StringHashable // Rows whose key is only in the old fingerprints.
+	Changed [][]csvcheck.StringHashable // Rows whose key is in both but whose hash changed, with the new hash.
+}
+
+// Returns the indices of the compared columns of the header in the order of the
+// header. These are the columns to use if given, and all columns but the ones to
+// ignore otherwise. The index and hash columns are never compared.
+func getComparedIndices(header, columnsToUse, columnsToIgnore []csvcheck.StringHashable) []int {
+	compared := columnsToUse != nil
+	selected := columnsToUse
+	if !compared {
+		selected = columnsToIgnore
+	}
+	marker := make(map[string]bool)
+	for _, column := range selected {
+		marker[column.StringHash()] = true
+	}
+
+	indices := []int{}
+	for i, column := range header {
+		name := column.StringHash()
+		if name == IndexColumnName || name == HashColumnName {
+			continue
+		}
+		if marker[name] == compared {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// Returns the indices of the compared columns of the header ordered by column
+// name, so that the hash does not depend on the order of the columns.
+func getFingerprintIndices(header, columnsToUse, columnsToIgnore []csvcheck.StringHashable) []int {
+	indices := getComparedIndices(header, columnsToUse, columnsToIgnore)
+	sort.SliceStable(indices, func(i, j int) bool {
+		return header[indices[i]].StringHash() < header[indices[j]].StringHash()
+	})
+	return indices
+}
+
+// Appends the length-prefixed strings to the buffer.
+func appendLengthPrefixed(buffer []byte, strings ...string) []byte {
+	for _, s := range strings {
+		buffer = strconv.AppendInt(buffer, int64(len(s)), 10)
+		buffer = append(buffer, ':')
+		buffer = append(buffer, s...)
+	}
+	return buffer
+}
+
+// Returns the fingerprint of the row as a hexadecimal string. The names and values
+// of the columns at the given indices are hashed in the order of the indices.
+// The buffer is reused between calls.
+func getRowFingerprint(header, row []csvcheck.StringHashable, indices []int, buffer []byte) (string, []byte) {
+	buffer = buffer[:0]
+	for _, i := range indices {
+		value := ""
+		if i < len(row) {
+			value = row[i].StringHash()
+		}
+		buffer = appendLengthPrefixed(buffer, header[i].StringHash(), value)
+	}
+	return fmt.Sprintf("%016x", xxhash.Sum64(buffer)), buffer
+}
+
+// Returns the csv array with the hash column added on the right side.
+func addHashColumn(arr [][]csvcheck.StringHashable, columnsToUse, columnsToIgnore []csvcheck.StringHashable) [][]csvcheck.StringHashable {
+	header := arr[0]
+	indices := getFingerprintIndices(header, columnsToUse, columnsToIgnore)
+
+	res := make([][]csvcheck.StringHashable, len(arr))
+	res[0] = append(append([]csvcheck.StringHashable{}, header...), csvcheck.BasicStringHashable(HashColumnName))
+	var buffer []byte
+	for i := 1; i < len(arr); i++ {
+		var fingerprint string
+		fingerprint, buffer = getRowFingerprint(header, arr[i], indices, buffer)
+		res[i] = append(append([]csvcheck.StringHashable{}, arr[i]...), csvcheck.BasicStringHashable(fingerprint))
+	}
+	return res
+}
+
+// Returns the positions of the columns in the header.
+func getColumnIndices(header, columns []csvcheck.StringHashable) []int {
+	positions := make(map[string]int)
+	for i, column := range header {
+		positions[column.StringHash()] = i
+	}
+
+	indices := make([]int, len(columns))
+	for i, column := range columns {
+		indices[i] = positions[column.StringHash()]
+	}
+	return indices
+}
+
+// Returns the values of the row as strings.
+func getRowStrings(row []csvcheck.StringHashable) []string {
+	res := make([]string, len(row))
+	for i, v := range row {
+		res[i] = v.StringHash()
+	}
+	return res
+}
+
+// Returns the values of the row at the indices encoded as a single string.
+func getKeyString(row []csvcheck.StringHashable, indices []int) string {
+	var buffer []byte
+	for _, i := range indices {
+		buffer = appendLengthPrefixed(buffer, row[i].StringHash())
+	}
+	return string(buffer)
+}
+
+// Returns a csv array with the key columns and the fingerprint of each row of the
+// csv array. The fingerprint is the same as the one added by the emit-hash option
+// for the same columns to use or ignore. Keys must be unique.
+func GetFingerprintArray(csvArray [][]csvcheck.StringHashable, cfg FingerprintConfig) ([][]csvcheck.StringHashable, error) {
+	if cfg.NoHeader {
+		csvArray = addGeneratedHeaderRow(csvArray)
+	}
+	if cfg.NormalizeHeaders {
+		csvArray = normalizeHeaderRow(csvArray)
+	}
+	if len(csvArray) == 0 {
+		return nil, fmt.Errorf("empty array")
+	}
+	header := csvArray[0]
+
+	keyColumns, err := resolveColumns("keycolumns", cfg.KeyColumns, cfg.NormalizeHeaders, header)
+	if err != nil {
+		return nil, err
+	}
+	columnsToUse, err := resolveColumns("usecolumns", cfg.ColumnsToUse, cfg.NormalizeHeaders, header)
+	if err != nil {
+		return nil, err
+	}
+	columnsToIgnore, err := resolveColumns("ignorecolumns", cfg.ColumnsToIgnore, cfg.NormalizeHeaders, header)
+	if err != nil {
+		return nil, err
+	}
+	columnsToUse, err = expandColumnPatterns("usecolumns", columnsToUse, header)
+	if err != nil {
+		return nil, err
+	}
+	columnsToIgnore, err = expandColumnPatterns("ignorecolumns", columnsToIgnore, header)
+	if err != nil {
+		return nil, err
+	}
+	for _, check := range []struct {
+		flagName string
+		columns  []csvcheck.StringHashable
+	}{
+		{"keycolumns", keyColumns},
+		{"usecolumns", columnsToUse},
+		{"ignorecolumns", columnsToIgnore},
+	} {
+		err = checkColumnsExist(check.flagName, check.columns, header)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	keyIndices := getColumnIndices(header, keyColumns)
+	hashIndices := getFingerprintIndices(header, columnsToUse, columnsToIgnore)
+
+	res := make([][]csvcheck.StringHashable, len(csvArray))
+	res[0] = append(append([]csvcheck.StringHashable{}, keyColumns...), csvcheck.BasicStringHashable(HashColumnName))
+	seen := make(map[string]int)
+	var buffer []byte
+	for i := 1; i < len(csvArray); i++ {
+		row := csvArray[i]
+		if len(row) != len(header) {
+			return nil, fmt.Errorf("row %d has %d columns but the header has %d", i, len(row), len(header))
+		}
+
+		key := getKeyString(row, keyIndices)
+		if previous, exists := seen[key]; exists {
+			return nil, fmt.Errorf("rows %d and %d have the same key", previous, i)
+		}
+		seen[key] = i
+
+		var fingerprint string
+		fingerprint, buffer = getRowFingerprint(header, row, hashIndices, buffer)
+		res[i] = make([]csvcheck.StringHashable, 0, len(keyIndices)+1)
+		for _, j := range keyIndices {
+			res[i] = append(res[i], row[j])
+		}
+		res[i] = append(res[i], csvcheck.BasicStringHashable(fingerprint))
+	}
+	return res, nil
+}
+
+// Returns the rows that are new, removed or changed in the new fingerprints compared
+// to the old ones. Both must have the same key columns followed by the hash column.
+func CompareFingerprints(oldArray, newArray [][]csvcheck.StringHashable) (FingerprintDiff, error) {
+	if len(oldArray) == 0 || len(newArray) == 0 {
+		return FingerprintDiff{}, fmt.Errorf("empty array")
+	}
+
+	header := newArray[0]
+	if len(header) < 2 || header[len(header)-1].StringHash() != HashColumnName {
+		return FingerprintDiff{}, fmt.Errorf("the last column of the fingerprints must be %s", HashColumnName)
+	}
+	oldHeader := getRowStrings(oldArray[0])
+	newHeader := getRowStrings(header)
+	if !slices.Equal(oldHeader, newHeader) {
+		return FingerprintDiff{}, fmt.Errorf("the old fingerprints have the columns %q but the new ones have %q", oldHeader, newHeader)
+	}
+
+	keyIndices := make([]int, len(header)-1)
+	for i := range keyIndices {
+		keyIndices[i] = i
+	}
+	hashIndex := len(header) - 1
+
+	oldHashes := make(map[string]string)
+	for _, row := range oldArray[1:] {
+		oldHashes[getKeyString(row, keyIndices)] = row[hashIndex].StringHash()
+	}
+
+	diff := FingerprintDiff{
+		New:     [][]csvcheck.StringHashable{header},
+		Removed: [][]csvcheck.StringHashable{header},
+		Changed: [][]csvcheck.StringHashable{header},
+	}
+	newKeys := make(map[string]bool)
+	for _, row := range newArray[1:] {
+		key := getKeyString(row, keyIndices)
+		newKeys[key] = true
+		oldHash, exists := oldHashes[key]
+		switch {
+		case !exists:
+			diff.New = append(diff.New, row)
+		case oldHash != row[hashIndex].StringHash():
+			diff.Changed = append(diff.Changed, row)
+		}
+	}
+	for _, row := range oldArray[1:] {
+		if !newKeys[getKeyString(row, keyIndices)] {
+			diff.Removed = append(diff.Removed, row)
+		}
+	}
+	return diff, nil
+}
+
+// Checks if the fingerprint options are valid.
+func (c FingerprintConfig) Validate() error {
+	if c.InputDir == "" {
+		return fmt.Errorf("inputdir must be given")
+	}
+	if c.File == "" {
+		return fmt.Errorf("file must be given")
+	}
+	if len(c.KeyColumns) == 0 {
+		return fmt.Errorf("keycolumns must be given")
+	}
+	if c.ColumnsToUse != nil && c.ColumnsToIgnore != nil {
+		return fmt.Errorf("usecolumns and ignorecolumns cannot be used together")
+	}
+	return checkWriteFlags(c.Overwrite, c.NoClobber)
+}
+
+// Returns how the fingerprint file is written.
+func (c FingerprintConfig) WriteOptions() WriteOptions {
+	return WriteOptions{Overwrite: c.Overwrite, FileMode: c.FileMode}
+}
+
+// Parses the arguments of the fingerprint command (without the program and command
+// names) into a FingerprintConfig. If help is requested, pflag.ErrHelp is returned.
+func ParseFingerprintArgs(args []string) (FingerprintConfig, error) {
+	var cfg FingerprintConfig
+	flags := pflag.NewFlagSet("csvcheckcli fingerprint", pflag.ContinueOnError)
+	flags.StringVarP(&cfg.InputDir, "inputdir", "d", "", "The directory containing the input file. This will be prepended to the input file path. Must be given.")
+	flags.StringVarP(&cfg.File, "file", "f", "", "The input file path to fingerprint. Must be given.")
+	flags.StringSliceVar(&cfg.KeyColumns, "keycolumns", nil, "The columns identifying a row. They are written next to the fingerprint of each row. Must be given.")
+	flags.StringSliceVarP(&cfg.ColumnsToUse, "usecolumns", "c", nil, "The columns to fingerprint.")
+	flags.StringSliceVarP(&cfg.ColumnsToIgnore, "ignorecolumns", "i", nil, "The columns to leave out of the fingerprint.")
+	flags.BoolVarP(&cfg.NormalizeHeaders, "normalizeheaders", "n", false, "Whether to match headers and given column names case-insensitively, ignoring surrounding whitespace, byte order marks and the kind of separators used (spaces, underscores, hyphens, dots).")
+	flags.BoolVar(&cfg.NoHeader, "noheader", false, fmt.Sprintf("Whether the csv file has no header row. Columns will be named %s1, %s2, ... and can also be referenced by position (#1, #2, ...).", GeneratedColumnPrefix, GeneratedColumnPrefix))
+	flags.StringVarP(&cfg.OutputDir, "outputdir", "o", "", "The directory to write the fingerprint file to. By default, the fingerprints are printed unless against is given.")
+	flags.BoolVarP(&cfg.AddTimestamp, "addtimestamp", "t", false, "Whether or not to add a timestamp to the output file name.")
+	flags.StringVar(&cfg.Against, "against", "", "The path of an earlier fingerprint file to report the new, removed and changed rows against.")
+	addWriteFlags(flags, &cfg.Overwrite, &cfg.NoClobber, &cfg.FileMode)
+	err := flags.Parse(args)
+	if err != nil {
+		return FingerprintConfig{}, err
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return FingerprintConfig{}, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,148 @@
+package csvcheckcli_test
+
+import (
+	"csvcheckcli/csvcheckcli"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFingerprintArrayColumnOrderIndependent(t *testing.T) {
+	csvArray1 := Get2DArrayFromCsvString(`
+id,name,amount
+1,a,10
+2,b,20
+`)
+	csvArray2 := Get2DArrayFromCsvString(`
+amount,id,name
+10,1,a
+25,2,b
+`)
+
+	cfg := csvcheckcli.FingerprintConfig{KeyColumns: []string{"id"}}
+	fingerprints1, err1 := csvcheckcli.GetFingerprintArray(csvArray1, cfg)
+	fingerprints2, err2 := csvcheckcli.GetFingerprintArray(csvArray2, cfg)
+
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Equal(t, 3, len(fingerprints1))
+	assert.Equal(t, []string{"id", csvcheckcli.HashColumnName}, []string{fingerprints1[0][0].StringHash(), fingerprints1[0][1].StringHash()})
+	assert.Equal(t, fingerprints1[1], fingerprints2[1])
+	assert.NotEqual(t, fingerprints1[2], fingerprints2[2])
+
+	cfg.ColumnsToIgnore = []string{"amount"}
+	fingerprints1, _ = csvcheckcli.GetFingerprintArray(csvArray1, cfg)
+	fingerprints2, _ = csvcheckcli.GetFingerprintArray(csvArray2, cfg)
+	assert.Equal(t, fingerprints1, fingerprints2)
+}
+
+func TestFingerprintArrayRoundTrip(t *testing.T) {
+	csvArray := [][]csvcheck.StringHashable{
+		csvcheck.GetRowFromRow([]string{"name", "amount"}),
+		csvcheck.GetRowFromRow([]string{"Smith, John", "10"}),
+		csvcheck.GetRowFromRow([]string{"say \"hi\"", "20"}),
+		csvcheck.GetRowFromRow([]string{"two\nlines", "30"}),
+	}
+	fingerprints, err := csvcheckcli.GetFingerprintArray(csvArray, csvcheckcli.FingerprintConfig{KeyColumns: []string{"name"}})
+	assert.Nil(t, err)
+
+	content, err := csvcheckcli.FormatCsvArray(fingerprints)
+	assert.Nil(t, err)
+	readFingerprints, err := csvcheckcli.ReadCsv(strings.NewReader(content))
+
+	assert.Nil(t, err)
+	assert.Equal(t, fingerprints, readFingerprints)
+	diff, err := csvcheckcli.CompareFingerprints(readFingerprints, fingerprints)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(diff.New))
+	assert.Equal(t, 1, len(diff.Removed))
+	assert.Equal(t, 1, len(diff.Changed))
+}
+
+func TestGetFingerprintArrayImproperInputs(t *testing.T) {
+	for i, data := range []struct {
+		csvString string
+		cfg       csvcheckcli.FingerprintConfig
+	}{
+		{csvString: "id,a\n1,2\n1,3\n", cfg: csvcheckcli.FingerprintConfig{KeyColumns: []string{"id"}}},
+		{csvString: "id,a\n1,2\n", cfg: csvcheckcli.FingerprintConfig{KeyColumns: []string{"ids"}}},
+		{csvString: "id,a\n1,2\n", cfg: csvcheckcli.FingerprintConfig{KeyColumns: []string{"#3"}}},
+		{csvString: "id,a\n1,2\n", cfg: csvcheckcli.FingerprintConfig{KeyColumns: []string{"id"}, ColumnsToUse: []string{"b"}}},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		_, err := csvcheckcli.GetFingerprintArray(Get2DArrayFromCsvString(data.csvString), data.cfg)
+		assert.NotNil(t, err, indexString)
+	}
+}
+
+func TestCompareFingerprints(t *testing.T) {
+	cfg := csvcheckcli.FingerprintConfig{KeyColumns: []string{"id"}}
+	oldFingerprints, _ := csvcheckcli.GetFingerprintArray(Get2DArrayFromCsvString(`
+id,name
+1,a
+2,b
+3,c
+`), cfg)
+	newFingerprints, _ := csvcheckcli.GetFingerprintArray(Get2DArrayFromCsvString(`
+id,name
+4,d
+2,bb
+1,a
+`), cfg)
+
+	diff, err := csvcheckcli.CompareFingerprints(oldFingerprints, newFingerprints)
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"4"}}, getKeyColumnValues(diff.New))
+	assert.Equal(t, [][]string{{"3"}}, getKeyColumnValues(diff.Removed))
+	assert.Equal(t, [][]string{{"2"}}, getKeyColumnValues(diff.Changed))
+	assert.Equal(t, newFingerprints[2], diff.Changed[1])
+
+	otherKeys, _ := csvcheckcli.GetFingerprintArray(Get2DArrayFromCsvString("name,id\na,1\n"), csvcheckcli.FingerprintConfig{KeyColumns: []string{"name"}})
+	_, err = csvcheckcli.CompareFingerprints(oldFingerprints, otherKeys)
+	assert.NotNil(t, err)
+}
+
+// Returns the values of the rows of the fingerprint array without the hash column.
+func getKeyColumnValues(fingerprints [][]csvcheck.StringHashable) [][]string {
+	res := [][]string{}
+	for _, row := range fingerprints[1:] {
+		values := []string{}
+		for _, v := range row[:len(row)-1] {
+			values = append(values, v.StringHash())
+		}
+		res = append(res, values)
+	}
+	return res
+}
+
+func TestGetResArraysEmitHashMatchesFingerprint(t *testing.T) {
+	csvArray1 := Get2DArrayFromCsvString(`
+id,name
+1,a
+2,b
+`)
+	csvArray2 := Get2DArrayFromCsvString(`
+name,id
+bb,2
+a,1
+`)
+
+	res1, res2, err := csvcheckcli.GetResArrays(csvArray1, csvArray2, userInputSolid{
+		method:    csvcheckcli.MethodStringMatch,
+		function:  csvcheckcli.FunctionStringDifferent,
+		autoAlign: true,
+		keepIndex: true,
+		emitHash:  true,
+	}.getUserInput())
+
+	fingerprints, _ := csvcheckcli.GetFingerprintArray(csvArray1, csvcheckcli.FingerprintConfig{KeyColumns: []string{"id"}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, csvcheckcli.HashColumnName, res1[0][3].StringHash())
+	assert.Equal(t, fingerprints[2][1], res1[1][3])
+	assert.NotEqual(t, res1[1][3], res2[1][3])
+}
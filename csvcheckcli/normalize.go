@@ -0,0 +1,97 @@
+package csvcheckcli
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// The unicode normalization forms values can be compared in.
+var normalizationForms = map[string]norm.Form{
+	"NFC":  norm.NFC,
+	"NFD":  norm.NFD,
+	"NFKC": norm.NFKC,
+	"NFKD": norm.NFKD,
+}
+
+// Returns the normalization form with the name, ignoring case.
+func getNormalizationForm(name string) (norm.Form, error) {
+	form, exists := normalizationForms[strings.ToUpper(name)]
+	if !exists {
+		return 0, fmt.Errorf("unsupported unicode normalization form %s, expected NFC, NFD, NFKC or NFKD", name)
+	}
+	return form, nil
+}
+
+// Returns a function bringing values to the normalization form and, if stripAccents
+// is true, removing their accents, or nil if neither is asked for. Accents are
+// removed by decomposing the values and dropping the combining marks, after which
+// they are in the normalization form, or NFC if none is given.
+func newValueNormalizer(formName string, stripAccents bool) (func(string) string, error) {
+	if formName == "" && !stripAccents {
+		return nil, nil
+	}
+
+	form := norm.NFC
+	if formName != "" {
+		var err error
+		form, err = getNormalizationForm(formName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var transformer transform.Transformer = form
+	if stripAccents {
+		transformer = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), form)
+	}
+	return func(s string) string {
+		if isASCII(s) {
+			return s
+		}
+		res, _, err := transform.String(transformer, s)
+		if err != nil {
+			return s
+		}
+		return res
+	}, nil
+}
+
+// Returns true iff the text only has ASCII characters, which no normalization changes.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a copy of the csv array with the values below the header normalized. The
+// header is left as it is, so that columns keep their names.
+func normalizeCsvArrayValues(arr [][]csvcheck.StringHashable, normalizer func(string) string) [][]csvcheck.StringHashable {
+	res := make([][]csvcheck.StringHashable, len(arr))
+	res[0] = arr[0]
+	for i := 1; i < len(arr); i++ {
+		res[i] = make([]csvcheck.StringHashable, len(arr[i]))
+		for j, value := range arr[i] {
+			res[i][j] = csvcheck.BasicStringHashable(normalizer(value.StringHash()))
+		}
+	}
+	return res
+}
+
+// Returns the result array with its rows replaced by the rows of the csv array at
+// their indices, so that results found with normalized values show the original ones.
+func restoreOriginalRows(res, csvArray [][]csvcheck.StringHashable, indices []int) [][]csvcheck.StringHashable {
+	for i := 1; i < len(res); i++ {
+		res[i] = csvArray[indices[i]]
+	}
+	return res
+}
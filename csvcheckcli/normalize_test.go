@@ -0,0 +1,116 @@
+package csvcheckcli_test
+
+import (
+	"context"
+	"csvcheckcli/csvcheckcli"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareUnicodeNormalize(t *testing.T) {
+	composed := "name,city\nJosé,München\nAnna,Berlin\n"
+	decomposed := "name,city\nJosé,München\nBob,Paris\n"
+
+	for i, data := range []struct {
+		csvString1       string
+		csvString2       string
+		unicodeNormalize string
+		stripAccents     bool
+		workers          int
+		expected1        string
+		expected2        string
+	}{
+		{
+			csvString1: composed,
+			csvString2: decomposed,
+			workers:    1,
+			expected1:  "name,city\n",
+			expected2:  "name,city\n",
+		},
+		{
+			csvString1:       composed,
+			csvString2:       decomposed,
+			unicodeNormalize: "NFC",
+			workers:          1,
+			expected1:        "name,city\nJosé,München\n",
+			expected2:        "name,city\nJosé,München\n",
+		},
+		{
+			csvString1:       composed,
+			csvString2:       decomposed,
+			unicodeNormalize: "nfd",
+			workers:          4,
+			expected1:        "name,city\nJosé,München\n",
+			expected2:        "name,city\nJosé,München\n",
+		},
+		{
+			csvString1:   composed,
+			csvString2:   "name,city\nJose,Munchen\n",
+			stripAccents: true,
+			workers:      1,
+			expected1:    "name,city\nJosé,München\n",
+			expected2:    "name,city\nJose,Munchen\n",
+		},
+		{
+			csvString1:       "word\nﬁle\n",
+			csvString2:       "word\nfile\n",
+			unicodeNormalize: "NFC",
+			workers:          1,
+			expected1:        "word\n",
+			expected2:        "word\n",
+		},
+		{
+			csvString1:       "word\nﬁle\n",
+			csvString2:       "word\nfile\n",
+			unicodeNormalize: "NFKC",
+			workers:          1,
+			expected1:        "word\nﬁle\n",
+			expected2:        "word\nfile\n",
+		},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		cfg := csvcheckcli.NewConfig(
+			csvcheckcli.WithFunction(csvcheckcli.FunctionStringCommon),
+			csvcheckcli.WithMethod(csvcheckcli.MethodStringMatch),
+			csvcheckcli.WithWorkers(data.workers),
+		)
+		cfg.UnicodeNormalize = data.unicodeNormalize
+		cfg.StripAccents = data.stripAccents
+
+		result, err := csvcheckcli.Compare(context.Background(), strings.NewReader(data.csvString1), strings.NewReader(data.csvString2), cfg)
+
+		assert.Nil(t, err, indexString)
+		assert.Equal(t, Get2DArrayFromCsvString(data.expected1), result.Rows1, indexString)
+		assert.Equal(t, Get2DArrayFromCsvString(data.expected2), result.Rows2, indexString)
+	}
+}
+
+func TestCompareUnicodeNormalizeKeepIndex(t *testing.T) {
+	cfg := csvcheckcli.NewConfig(
+		csvcheckcli.WithFunction(csvcheckcli.FunctionStringDifferent),
+		csvcheckcli.WithKeepIndex(true),
+	)
+	cfg.StripAccents = true
+
+	result, err := csvcheckcli.Compare(context.Background(), strings.NewReader("a\nb\né\nc\n"), strings.NewReader("a\ne\n"), cfg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, Get2DArrayFromCsvString(fmt.Sprintf("a,%s\nb,1\nc,3\n", csvcheckcli.IndexColumnName)), result.Rows1)
+	assert.Equal(t, Get2DArrayFromCsvString(fmt.Sprintf("a,%s\n", csvcheckcli.IndexColumnName)), result.Rows2)
+}
+
+func TestGetDifferenceStatsUnicodeNormalize(t *testing.T) {
+	csvArray1 := Get2DArrayFromCsvString("id,name\n1,José\n2,Anna\n")
+	csvArray2 := Get2DArrayFromCsvString("id,name\n1,José\n2,Anne\n")
+	cfg := csvcheckcli.NewConfig(csvcheckcli.WithFunction(csvcheckcli.FunctionStringStats), csvcheckcli.WithKeyColumns("id"))
+	cfg.UnicodeNormalize = "NFC"
+
+	stats, err := csvcheckcli.GetDifferenceStats(csvArray1, csvArray2, cfg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, stats.Columns[0].Differing)
+	assert.Equal(t, []string{"2"}, stats.Columns[0].ExampleKeys)
+}
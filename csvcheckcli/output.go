@@ -0,0 +1,156 @@
+package csvcheckcli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/spf13/pflag"
+)
+
+// The permissions of written files when none are given.
+const DefaultFileMode os.FileMode = 0644
+
+// Returned by WriteFile when the file already exists and may not be overwritten.
+var ErrFileExists = errors.New("file already exists")
+
+// Links a complete temporary file to its path. A variable so that tests can make it fail.
+var linkFile = os.Link
+
+// For holding how WriteFile writes a file.
+type WriteOptions struct {
+	Overwrite bool        // Whether to replace an existing file.
+	FileMode  os.FileMode // The permissions of the file, or DefaultFileMode if 0.
+	Encoding  string      // The encoding the content is written in, or UTF-8 if empty.
+}
+
+// Writes the content to a temporary file next to the path and moves it to the
+// path once it is complete, so the file is never left half-written. The directory
+// is created if it does not exist. If the file already exists and may not be
+// overwritten, an error wrapping ErrFileExists is returned. The temporary file is
+// then linked to the path rather than renamed, as linking fails if the file was
+// created in the meantime while renaming would replace it. On file systems without
+// hard links, the path is instead claimed by creating it exclusively and the temporary
+// file is renamed over it, so the file may briefly be seen empty.
+func WriteFile(filePath string, content string, options WriteOptions) (err error) {
+	fileMode := options.FileMode
+	if fileMode == 0 {
+		fileMode = DefaultFileMode
+	}
+	content, err = EncodeString(content, options.Encoding)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(filePath)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(dir, "."+filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	_, err = file.WriteString(content)
+	if err != nil {
+		return err
+	}
+	err = file.Chmod(fileMode)
+	if err != nil {
+		return err
+	}
+	err = file.Sync()
+	if err != nil {
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+	if options.Overwrite {
+		return os.Rename(file.Name(), filePath)
+	}
+
+	err = linkFile(file.Name(), filePath)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%w: %s", ErrFileExists, filePath)
+	}
+	if err != nil {
+		return renameExclusive(file.Name(), filePath, fileMode)
+	}
+	return os.Remove(file.Name())
+}
+
+// Moves the temporary file to the path if the path does not exist yet, by creating
+// it exclusively before renaming the temporary file over it. If the file already
+// exists, an error wrapping ErrFileExists is returned.
+func renameExclusive(tempPath, filePath string, fileMode os.FileMode) error {
+	placeholder, err := os.OpenFile(filePath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, fileMode)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%w: %s", ErrFileExists, filePath)
+	}
+	if err != nil {
+		return err
+	}
+	err = placeholder.Close()
+	if err == nil {
+		err = os.Rename(tempPath, filePath)
+	}
+	if err != nil {
+		os.Remove(filePath)
+		return err
+	}
+	return nil
+}
+
+// A pflag.Value for file permissions given in octal, such as 0600.
+type fileModeValue struct {
+	mode *os.FileMode
+}
+
+func (v fileModeValue) String() string {
+	if v.mode == nil {
+		return ""
+	}
+	return fmt.Sprintf("%04o", uint32(*v.mode))
+}
+
+func (v fileModeValue) Set(s string) error {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0777 {
+		return fmt.Errorf("invalid file mode %q, expected permissions in octal such as 0644", s)
+	}
+	*v.mode = os.FileMode(mode)
+	return nil
+}
+
+func (v fileModeValue) Type() string {
+	return "octal"
+}
+
+// Adds the flags controlling how output files are written to the flag set.
+func addWriteFlags(flags *pflag.FlagSet, overwrite, noClobber *bool, fileMode *os.FileMode) {
+	*fileMode = DefaultFileMode
+	flags.BoolVar(overwrite, "overwrite", false, "Whether to replace output files that already exist. By default, existing files are not touched and an error is given.")
+	flags.BoolVar(noClobber, "no-clobber", false, "Whether to skip writing output files that already exist instead of giving an error.")
+	flags.Var(fileModeValue{fileMode}, "filemode", "The permissions of the output files in octal, such as 0600.")
+}
+
+// Returns an error if both overwrite and no-clobber are given.
+func checkWriteFlags(overwrite, noClobber bool) error {
+	if overwrite && noClobber {
+		return fmt.Errorf("overwrite and no-clobber cannot be used together")
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package csvcheckcli_test
+
+import (
+	"csvcheckcli/csvcheckcli"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sub", "res.csv")
+
+	err := csvcheckcli.WriteFile(path, "a\n1\n", csvcheckcli.WriteOptions{})
+	assert.Nil(t, err)
+	content, _ := os.ReadFile(path)
+	assert.Equal(t, "a\n1\n", string(content))
+	info, _ := os.Stat(path)
+	assert.Equal(t, csvcheckcli.DefaultFileMode, info.Mode().Perm())
+
+	err = csvcheckcli.WriteFile(path, "a\n2\n", csvcheckcli.WriteOptions{})
+	assert.ErrorIs(t, err, csvcheckcli.ErrFileExists)
+	content, _ = os.ReadFile(path)
+	assert.Equal(t, "a\n1\n", string(content))
+
+	err = csvcheckcli.WriteFile(path, "a\n3\n", csvcheckcli.WriteOptions{Overwrite: true, FileMode: 0600})
+	assert.Nil(t, err)
+	content, _ = os.ReadFile(path)
+	assert.Equal(t, "a\n3\n", string(content))
+	info, _ = os.Stat(path)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	entries, _ := os.ReadDir(filepath.Join(dir, "sub"))
+	assert.Equal(t, 1, len(entries))
+}
+
+func TestWriteFileConcurrentWritersDoNotOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "res.csv")
+
+	var wg sync.WaitGroup
+	errs := make([]error, 20)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = csvcheckcli.WriteFile(path, fmt.Sprintf("a\n%d\n", i), csvcheckcli.WriteOptions{})
+		}()
+	}
+	wg.Wait()
+
+	written := -1
+	for i, err := range errs {
+		if err == nil {
+			assert.Equal(t, -1, written)
+			written = i
+			continue
+		}
+		assert.ErrorIs(t, err, csvcheckcli.ErrFileExists)
+	}
+	content, _ := os.ReadFile(path)
+	assert.Equal(t, fmt.Sprintf("a\n%d\n", written), string(content))
+
+	entries, _ := os.ReadDir(dir)
+	assert.Equal(t, 1, len(entries))
+}
+
+func TestWriteFileWithoutHardLinks(t *testing.T) {
+	restore := csvcheckcli.SetLinkFile(func(oldname, newname string) error {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: errors.ErrUnsupported}
+	})
+	defer restore()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "res.csv")
+
+	err := csvcheckcli.WriteFile(path, "a\n1\n", csvcheckcli.WriteOptions{FileMode: 0600})
+	assert.Nil(t, err)
+	content, _ := os.ReadFile(path)
+	assert.Equal(t, "a\n1\n", string(content))
+	info, _ := os.Stat(path)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	err = csvcheckcli.WriteFile(path, "a\n2\n", csvcheckcli.WriteOptions{})
+	assert.ErrorIs(t, err, csvcheckcli.ErrFileExists)
+	content, _ = os.ReadFile(path)
+	assert.Equal(t, "a\n1\n", string(content))
+
+	entries, _ := os.ReadDir(dir)
+	assert.Equal(t, 1, len(entries))
+}
+
+func TestParseArgsWriteOptions(t *testing.T) {
+	args := []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "common"}
+
+	cfg, err := csvcheckcli.ParseArgs(args)
+	assert.Nil(t, err)
+	assert.Equal(t, csvcheckcli.WriteOptions{FileMode: csvcheckcli.DefaultFileMode}, cfg.WriteOptions())
+
+	cfg, err = csvcheckcli.ParseArgs(append(args, "--overwrite", "--filemode", "0600"))
+	assert.Nil(t, err)
+	assert.Equal(t, csvcheckcli.WriteOptions{Overwrite: true, FileMode: 0600}, cfg.WriteOptions())
+
+	_, err = csvcheckcli.ParseArgs(append(args, "--overwrite", "--no-clobber"))
+	assert.NotNil(t, err)
+
+	_, err = csvcheckcli.ParseArgs(append(args, "--filemode", "0999"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,200 @@
+package csvcheckcli
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The output name templates used when none is given, with and without a timestamp.
+const (
+	DefaultOutputName                = "csvcheck_{file}.csv"
+	DefaultTimestampOutputName       = "csvcheck_{file}_{date}.csv"
+	DefaultReportOutputName          = "csvcheck_{function}_{file1}_{file2}.csv"
+	DefaultTimestampReportOutputName = "csvcheck_{function}_{file1}_{file2}_{date}.csv"
+)
+
+// The layout of {date} when none is given.
+const defaultOutputNameDateLayout = "2006_01_02_15_04_05"
+
+// The placeholders of output name templates and whether they take an argument.
+var outputNamePlaceholders = map[string]bool{
+	"function": false,
+	"method":   false,
+	"file":     false,
+	"file1":    false,
+	"file2":    false,
+	"ext":      false,
+	"dir":      false,
+	"runid":    false,
+	"date":     true,
+}
+
+// For holding the values the placeholders of an output name template are replaced by.
+type OutputNameData struct {
+	Function string
+	Method   string
+	File1    string // The path of the first input file.
+	File2    string // The path of the second input file.
+	File     int    // 1 or 2 for the results of the first or second file, 0 for results of both.
+	InputDir string // The input directory, whose name {dir} is replaced by.
+	Time     time.Time
+	RunID    string
+}
+
+// Returns a random id for a run, for the {runid} placeholder.
+func NewRunID() string {
+	id := make([]byte, 4)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Splits the output name template into literal text and placeholders. Placeholders
+// are returned with their braces.
+func splitOutputName(template string) ([]string, error) {
+	parts := []string{}
+	for len(template) > 0 {
+		start := strings.IndexAny(template, "{}")
+		if start < 0 {
+			parts = append(parts, template)
+			break
+		}
+		if template[start] == '}' {
+			return nil, fmt.Errorf("unmatched } in output name %q", template)
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unmatched { in output name %q", template)
+		}
+		end += start
+
+		if start > 0 {
+			parts = append(parts, template[:start])
+		}
+		parts = append(parts, template[start:end+1])
+		template = template[end+1:]
+	}
+	return parts, nil
+}
+
+// Returns the name and argument of a placeholder such as {date:20060102}.
+func parseOutputNamePlaceholder(part string) (string, string, bool) {
+	if !strings.HasPrefix(part, "{") {
+		return "", "", false
+	}
+	name, argument, _ := strings.Cut(part[1:len(part)-1], ":")
+	return name, argument, true
+}
+
+// Checks that the output name template only has known placeholders and matching braces.
+func CheckOutputName(template string) error {
+	parts, err := splitOutputName(template)
+	if err != nil {
+		return err
+	}
+
+	for _, part := range parts {
+		name, argument, isPlaceholder := parseOutputNamePlaceholder(part)
+		if !isPlaceholder {
+			continue
+		}
+		takesArgument, exists := outputNamePlaceholders[name]
+		if !exists {
+			return fmt.Errorf("unknown placeholder %s in output name %q", part, template)
+		}
+		if argument != "" && !takesArgument {
+			return fmt.Errorf("placeholder {%s} in output name %q does not take an argument", name, template)
+		}
+	}
+	return nil
+}
+
+// Returns the file name without its directory and extension, and the extension
+// without the dot, which is csv if the file has none.
+func splitFileName(filePath string) (string, string) {
+	fileName := filepath.Base(filePath)
+	ext := filepath.Ext(fileName)
+	if ext == "" || ext == "." {
+		return strings.TrimSuffix(fileName, ext), "csv"
+	}
+	return strings.TrimSuffix(fileName, ext), ext[1:]
+}
+
+// Returns the output name template with its placeholders replaced. {file} and
+// {ext} refer to the input file the results are of, and for results of both files
+// {file} is both file names joined by an underscore.
+func FormatOutputName(template string, data OutputNameData) (string, error) {
+	parts, err := splitOutputName(template)
+	if err != nil {
+		return "", err
+	}
+
+	fileName1, ext1 := splitFileName(data.File1)
+	fileName2, ext2 := splitFileName(data.File2)
+	fileName, ext := fileName1+"_"+fileName2, ext1
+	if data.File == 2 {
+		fileName, ext = fileName2, ext2
+	} else if data.File == 1 {
+		fileName = fileName1
+	}
+
+	var builder strings.Builder
+	for _, part := range parts {
+		name, argument, isPlaceholder := parseOutputNamePlaceholder(part)
+		if !isPlaceholder {
+			builder.WriteString(part)
+			continue
+		}
+
+		switch name {
+		case "function":
+			builder.WriteString(data.Function)
+		case "method":
+			builder.WriteString(data.Method)
+		case "file":
+			builder.WriteString(fileName)
+		case "file1":
+			builder.WriteString(fileName1)
+		case "file2":
+			builder.WriteString(fileName2)
+		case "ext":
+			builder.WriteString(ext)
+		case "dir":
+			inputDir, err := filepath.Abs(data.InputDir)
+			if err != nil {
+				return "", err
+			}
+			builder.WriteString(filepath.Base(inputDir))
+		case "runid":
+			builder.WriteString(data.RunID)
+		case "date":
+			if argument == "" {
+				argument = defaultOutputNameDateLayout
+			}
+			builder.WriteString(data.Time.Format(argument))
+		default:
+			return "", fmt.Errorf("unknown placeholder %s in output name %q", part, template)
+		}
+	}
+	return builder.String(), nil
+}
+
+// Returns the output name template of the config, or the default one if none is
+// given. report is true for functions writing a single file for both input files.
+func (c Config) OutputNameTemplate(report bool) string {
+	switch {
+	case c.OutputName != "":
+		return c.OutputName
+	case report && c.AddTimestamp:
+		return DefaultTimestampReportOutputName
+	case report:
+		return DefaultReportOutputName
+	case c.AddTimestamp:
+		return DefaultTimestampOutputName
+	default:
+		return DefaultOutputName
+	}
+}
@@ -0,0 +1,80 @@
+package csvcheckcli_test
+
+import (
+	"csvcheckcli/csvcheckcli"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatOutputName(t *testing.T) {
+	data := csvcheckcli.OutputNameData{
+		Function: csvcheckcli.FunctionStringDifferent,
+		Method:   csvcheckcli.MethodStringMatch,
+		File1:    "in/old.csv",
+		File2:    "in/new.txt",
+		InputDir: "/data/exports/",
+		Time:     time.Date(2024, 9, 25, 13, 51, 22, 0, time.UTC),
+		RunID:    "0a1b2c3d",
+	}
+
+	for i, d := range []struct {
+		template string
+		file     int
+		expected string
+	}{
+		{template: csvcheckcli.DefaultOutputName, file: 1, expected: "csvcheck_old.csv"},
+		{template: csvcheckcli.DefaultTimestampOutputName, file: 2, expected: "csvcheck_new_2024_09_25_13_51_22.csv"},
+		{template: csvcheckcli.DefaultReportOutputName, file: 0, expected: "csvcheck_different_old_new.csv"},
+		{template: "{function}_{method}_{file}_{date:20060102}.{ext}", file: 2, expected: "different_match_new_20240925.txt"},
+		{template: "{dir}/{runid}_{file}.csv", file: 0, expected: "exports/0a1b2c3d_old_new.csv"},
+		{template: "{file2}_vs_{file1}", file: 1, expected: "new_vs_old"},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		data.File = d.file
+		res, err := csvcheckcli.FormatOutputName(d.template, data)
+		assert.Nil(t, err, indexString)
+		assert.Equal(t, d.expected, res, indexString)
+	}
+}
+
+func TestCheckOutputName(t *testing.T) {
+	for i, data := range []struct {
+		template    string
+		expectError bool
+	}{
+		{template: "{function}_{method}_{file}_{date:20060102}.{ext}", expectError: false},
+		{template: "results.csv", expectError: false},
+		{template: "{date}_{runid}_{dir}_{file1}_{file2}.csv", expectError: false},
+		{template: "{foo}.csv", expectError: true},
+		{template: "{file.csv", expectError: true},
+		{template: "file}.csv", expectError: true},
+		{template: "{file:x}.csv", expectError: true},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		err := csvcheckcli.CheckOutputName(data.template)
+		if data.expectError {
+			assert.NotNil(t, err, indexString)
+		} else {
+			assert.Nil(t, err, indexString)
+		}
+	}
+}
+
+func TestParseArgsOutputName(t *testing.T) {
+	args := []string{"-d", "dir", "-f", "file1.csv,file2.csv", "-F", "common"}
+
+	cfg, err := csvcheckcli.ParseArgs(append(args, "--outputname", "{file}_{runid}.csv"))
+	assert.Nil(t, err)
+	assert.Equal(t, "{file}_{runid}.csv", cfg.OutputNameTemplate(false))
+
+	cfg, err = csvcheckcli.ParseArgs(append(args, "-t"))
+	assert.Nil(t, err)
+	assert.Equal(t, csvcheckcli.DefaultTimestampOutputName, cfg.OutputNameTemplate(false))
+	assert.Equal(t, csvcheckcli.DefaultTimestampReportOutputName, cfg.OutputNameTemplate(true))
+
+	_, err = csvcheckcli.ParseArgs(append(args, "-t", "--outputname", "{file}.csv"))
+	assert.NotNil(t, err)
+}
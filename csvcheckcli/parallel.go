@@ -0,0 +1,240 @@
+package csvcheckcli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+	"github.com/cespare/xxhash"
+)
+
+// A hash key for a row. The length of the encoded row is kept
+// alongside the hash to make collisions less likely.
+type rowKey struct {
+	hash   uint64
+	length int
+}
+
+// Returns a hash key for a row. Every cell is prefixed by its length so that
+// different rows cannot have the same encoding. The buffer is reused between calls.
+func getRowKey(row []csvcheck.StringHashable, buffer []byte) (rowKey, []byte) {
+	buffer = buffer[:0]
+	for _, cell := range row {
+		s := cell.StringHash()
+		buffer = strconv.AppendInt(buffer, int64(len(s)), 10)
+		buffer = append(buffer, ':')
+		buffer = append(buffer, s...)
+	}
+	return rowKey{hash: xxhash.Sum64(buffer), length: len(buffer)}, buffer
+}
+
+// Returns the hash keys of the rows. The rows are split into contiguous shards
+// that are hashed by workers goroutines. The total number of rows hashed so far is
+// passed to onProgress every so often, and hashing stops early with the context's
+// error if it is cancelled.
+func hashRows(ctx context.Context, rows [][]csvcheck.StringHashable, workers int, onProgress func(hashed int64)) ([]rowKey, error) {
+	keys := make([]rowKey, len(rows))
+	workers = max(1, min(workers, len(rows)))
+	shardSize := (len(rows) + workers - 1) / workers
+
+	var hashed atomic.Int64
+	var wg sync.WaitGroup
+	for start := 0; start < len(rows); start += shardSize {
+		end := min(start+shardSize, len(rows))
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			var buffer []byte
+			for i := start; i < end; i++ {
+				keys[i], buffer = getRowKey(rows[i], buffer)
+				if (i-start+1)%progressInterval == 0 {
+					onProgress(hashed.Add(progressInterval))
+					if ctx.Err() != nil {
+						return
+					}
+				}
+			}
+			onProgress(hashed.Add(int64((end - start) % progressInterval)))
+		}(start, end)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Returns true iff the two rows contain the same columns in any order.
+func columnsArePermutationsOfEachOther(columns1, columns2 []csvcheck.StringHashable) bool {
+	if len(columns1) != len(columns2) {
+		return false
+	}
+
+	cnt := make(map[string]int)
+	for _, v := range columns1 {
+		cnt[v.StringHash()]++
+	}
+	for _, v := range columns2 {
+		cnt[v.StringHash()]--
+	}
+	for _, v := range cnt {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the rows below the columns row of both arrays restricted to the columns
+// being compared, with the columns of the second array in the order of the first.
+func getBelowComparisonArrays(csvArray1, csvArray2 [][]csvcheck.StringHashable, options csvcheck.Options) ([][]csvcheck.StringHashable, [][]csvcheck.StringHashable, error) {
+	err := csvcheck.CheckForProperCsvArray(csvArray1)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = csvcheck.CheckForProperCsvArray(csvArray2)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = options.CheckAttributes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	comparisonArray1 := csvArray1
+	comparisonArray2 := csvArray2
+	if options.UseColumns != nil {
+		comparisonArray1, _ = csvcheck.KeepColumns(csvArray1, options.UseColumns)
+		comparisonArray2, _ = csvcheck.KeepColumns(csvArray2, options.UseColumns)
+	} else if options.IgnoreColumns != nil {
+		comparisonArray1, _ = csvcheck.IgnoreColumns(csvArray1, options.IgnoreColumns)
+		comparisonArray2, _ = csvcheck.IgnoreColumns(csvArray2, options.IgnoreColumns)
+	}
+
+	columns1 := comparisonArray1[0]
+	columns2 := comparisonArray2[0]
+	if len(columns1) == 0 || len(columns2) == 0 {
+		return nil, nil, fmt.Errorf("no columns to compare")
+	} else if !columnsArePermutationsOfEachOther(columns1, columns2) {
+		return nil, nil, fmt.Errorf("check the columns being compared")
+	}
+
+	comparisonArray2, _ = csvcheck.RearrangeColumns(comparisonArray2, columns1)
+
+	return comparisonArray1[1:], comparisonArray2[1:], nil
+}
+
+// Returns, for every row, how many rows with the same key come before it.
+func getOccurrenceRanks(keys []rowKey) ([]int, map[rowKey]int) {
+	ranks := make([]int, len(keys))
+	counts := make(map[rowKey]int)
+	for i, key := range keys {
+		ranks[i] = counts[key]
+		counts[key]++
+	}
+	return ranks, counts
+}
+
+// Returns the indices, in increasing order, of the rows in the first array selected by the method.
+// For the common function, rows whose key appears in the other array are selected
+// (with the match method, only as many of them as the other array has). For the
+// different function, the remaining rows are selected.
+func getSelectedIndices(keys1, keys2 []rowKey, method int, common bool) []int {
+	indices := []int{}
+
+	if method == csvcheck.MethodDirect {
+		for i := range keys1 {
+			equal := i < len(keys2) && keys1[i] == keys2[i]
+			if equal == common {
+				indices = append(indices, i)
+			}
+		}
+		return indices
+	}
+
+	ranks1, _ := getOccurrenceRanks(keys1)
+	_, counts2 := getOccurrenceRanks(keys2)
+	for i, key := range keys1 {
+		var matched bool
+		if method == csvcheck.MethodMatch {
+			matched = ranks1[i] < counts2[key]
+		} else {
+			matched = counts2[key] > 0
+		}
+		if matched == common {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// Returns the rows of the array at the given below indices together with
+// the columns row, and their indices in the array.
+func getRowsAtBelowIndices(arr [][]csvcheck.StringHashable, belowIndices []int) ([][]csvcheck.StringHashable, []int) {
+	rows := make([][]csvcheck.StringHashable, len(belowIndices)+1)
+	indices := make([]int, len(belowIndices)+1)
+	rows[0] = arr[0]
+	for i, index := range belowIndices {
+		rows[i+1] = arr[index+1]
+		indices[i+1] = index + 1
+	}
+	return rows, indices
+}
+
+// Returns the rows of both arrays selected by the function and the options with
+// the row hashing sharded across workers goroutines.
+func getRowsParallel(ctx context.Context, csvArray1, csvArray2 [][]csvcheck.StringHashable, options csvcheck.Options, workers int, common bool, tracker *progressTracker) ([][]csvcheck.StringHashable, [][]csvcheck.StringHashable, []int, []int, error) {
+	belowArray1, belowArray2, err := getBelowComparisonArrays(csvArray1, csvArray2, options)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	var keys1, keys2 []rowKey
+	var err1, err2 error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		keys1, err1 = hashRows(ctx, belowArray1, workers, func(hashed int64) {
+			tracker.reportHashing(1, hashed, int64(len(belowArray1)))
+		})
+	}()
+	go func() {
+		defer wg.Done()
+		keys2, err2 = hashRows(ctx, belowArray2, workers, func(hashed int64) {
+			tracker.reportHashing(2, hashed, int64(len(belowArray2)))
+		})
+	}()
+	wg.Wait()
+	if err1 != nil {
+		return nil, nil, nil, nil, err1
+	}
+	if err2 != nil {
+		return nil, nil, nil, nil, err2
+	}
+
+	belowIndices1 := getSelectedIndices(keys1, keys2, options.Method, common)
+	belowIndices2 := getSelectedIndices(keys2, keys1, options.Method, common)
+
+	res1, indices1 := getRowsAtBelowIndices(csvArray1, belowIndices1)
+	res2, indices2 := getRowsAtBelowIndices(csvArray2, belowIndices2)
+
+	return res1, res2, indices1, indices2, nil
+}
+
+// Works like csvcheck.GetCommonRows with the row hashing sharded across workers
+// goroutines. The rows and indices of the results are always in their original order.
+func GetCommonRowsParallel(csvArray1, csvArray2 [][]csvcheck.StringHashable, options csvcheck.Options, workers int) ([][]csvcheck.StringHashable, [][]csvcheck.StringHashable, []int, []int, error) {
+	return getRowsParallel(context.Background(), csvArray1, csvArray2, options, workers, true, nil)
+}
+
+// Works like csvcheck.GetDifferentRows with the row hashing sharded across workers
+// goroutines. The rows and indices of the results are always in their original order.
+func GetDifferentRowsParallel(csvArray1, csvArray2 [][]csvcheck.StringHashable, options csvcheck.Options, workers int) ([][]csvcheck.StringHashable, [][]csvcheck.StringHashable, []int, []int, error) {
+	return getRowsParallel(context.Background(), csvArray1, csvArray2, options, workers, false, nil)
+}
@@ -0,0 +1,134 @@
+package csvcheckcli_test
+
+import (
+	"csvcheckcli/csvcheckcli"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a synthetic csv array with the given number of rows. Cells are
+// drawn from a small range of values so that rows repeat.
+func getSyntheticCsvArray(rows int, columns []string, valueRange int, seed int64) [][]csvcheck.StringHashable {
+	random := rand.New(rand.NewSource(seed))
+	res := make([][]csvcheck.StringHashable, rows+1)
+	res[0] = csvcheck.GetRowFromRow(columns)
+	for i := 1; i <= rows; i++ {
+		row := make([]csvcheck.StringHashable, len(columns))
+		for j := range row {
+			row[j] = csvcheck.BasicStringHashable(fmt.Sprintf("%d", random.Intn(valueRange)))
+		}
+		res[i] = row
+	}
+	return res
+}
+
+func TestGetRowsParallelMatchesCsvcheck(t *testing.T) {
+	arr1 := getSyntheticCsvArray(500, []string{"a", "b", "c"}, 3, 1)
+	arr2 := getSyntheticCsvArray(400, []string{"c", "a", "b"}, 3, 2)
+
+	for i, data := range []struct {
+		method     int
+		useColumns []string
+	}{
+		{method: csvcheck.MethodMatch},
+		{method: csvcheck.MethodSet},
+		{method: csvcheck.MethodDirect},
+		{method: csvcheck.MethodMatch, useColumns: []string{"b", "a"}},
+		{method: csvcheck.MethodSet, useColumns: []string{"c"}},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		options := csvcheck.Options{
+			Method:      data.method,
+			UseColumns:  csvcheck.GetRowFromRow(data.useColumns),
+			SortIndices: true,
+		}
+
+		expected1, expected2, expectedIndices1, expectedIndices2, err := csvcheck.GetCommonRows(arr1, arr2, options)
+		assert.Nil(t, err, indexString)
+		res1, res2, indices1, indices2, err := csvcheckcli.GetCommonRowsParallel(arr1, arr2, options, 4)
+		assert.Nil(t, err, indexString)
+		assert.Equal(t, expected1, res1, indexString)
+		assert.Equal(t, expected2, res2, indexString)
+		assert.Equal(t, expectedIndices1, indices1, indexString)
+		assert.Equal(t, expectedIndices2, indices2, indexString)
+
+		expected1, expected2, expectedIndices1, expectedIndices2, err = csvcheck.GetDifferentRows(arr1, arr2, options)
+		assert.Nil(t, err, indexString)
+		res1, res2, indices1, indices2, err = csvcheckcli.GetDifferentRowsParallel(arr1, arr2, options, 4)
+		assert.Nil(t, err, indexString)
+		assert.Equal(t, expected1, res1, indexString)
+		assert.Equal(t, expected2, res2, indexString)
+		assert.Equal(t, expectedIndices1, indices1, indexString)
+		assert.Equal(t, expectedIndices2, indices2, indexString)
+	}
+}
+
+func TestGetResArraysDifferentSetKeepIndexWorkers(t *testing.T) {
+	input := userInputSolid{
+		inputDir:         "/path/to/input/dir",
+		files:            []string{"file1.csv", "file2.csv"},
+		method:           csvcheckcli.MethodStringSet,
+		function:         csvcheckcli.FunctionStringDifferent,
+		keepIndex:        true,
+		useCommonColumns: true,
+		workers:          3,
+	}.getUserInput()
+
+	arr1 := Get2DArrayFromCsvString(`
+a,b,c
+-1,-1,-1
+1,2,3
+4,5,6
+7,8,9
+7,8,9
+`)
+	arr2 := Get2DArrayFromCsvString(`
+a,b,c
+7,8,9
+1,2,3
+1,2,3
+4,5,6
+7,8,9
+7,8,9
+10,10,10
+`)
+	res1, res2, err := csvcheckcli.GetResArrays(arr1, arr2, input)
+
+	expected1 := Get2DArrayFromCsvString(fmt.Sprintf(`
+a,b,c,%s
+-1,-1,-1,1
+`, csvcheckcli.IndexColumnName))
+
+	expected2 := Get2DArrayFromCsvString(fmt.Sprintf(`
+a,b,c,%s
+10,10,10,7
+`, csvcheckcli.IndexColumnName))
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected1, res1)
+	assert.Equal(t, expected2, res2)
+}
+
+func BenchmarkGetDifferentRows(b *testing.B) {
+	columns := []string{"a", "b", "c", "d", "e", "f"}
+	arr1 := getSyntheticCsvArray(200000, columns, 1000, 1)
+	arr2 := getSyntheticCsvArray(200000, columns, 1000, 2)
+	options := csvcheck.Options{Method: csvcheck.MethodMatch, SortIndices: true}
+
+	b.Run("csvcheck", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _, _, _, _ = csvcheck.GetDifferentRows(arr1, arr2, options)
+		}
+	})
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _, _, _, _ = csvcheckcli.GetDifferentRowsParallel(arr1, arr2, options, workers)
+			}
+		})
+	}
+}
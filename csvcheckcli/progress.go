@@ -0,0 +1,227 @@
+package csvcheckcli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The stages of a comparison that progress is reported for.
+const (
+	ProgressStageReading = "reading"
+	ProgressStageHashing = "hashing"
+)
+
+// The number of rows processed between progress updates and cancellation checks.
+const progressInterval = 10000
+
+// The width of the bar drawn by ProgressBar.
+const progressBarWidth = 20
+
+// For holding the progress of a stage of a comparison for one of the files.
+type Progress struct {
+	Stage    string        // One of the ProgressStage constants.
+	File     int           // 1 or 2 for the first or second file.
+	Rows     int64         // The number of rows processed so far.
+	Fraction float64       // The fraction of the stage done between 0 and 1, or -1 if unknown.
+	Elapsed  time.Duration // The time since the stage started.
+	ETA      time.Duration // The estimated time left in the stage, or -1 if unknown.
+}
+
+// For receiving progress updates of a comparison. Updates for both files are
+// sent concurrently, so implementations must be safe for concurrent use.
+type ProgressReporter interface {
+	ReportProgress(progress Progress)
+}
+
+// An adapter to allow the use of ordinary functions as progress reporters.
+type ProgressFunc func(progress Progress)
+
+func (f ProgressFunc) ReportProgress(progress Progress) {
+	f(progress)
+}
+
+// Returns the size in bytes of the data src reads, or -1 if it is unknown.
+func sourceSize(src io.Reader) int64 {
+	switch v := src.(type) {
+	case interface{ Stat() (os.FileInfo, error) }:
+		info, err := v.Stat()
+		if err == nil && info.Mode().IsRegular() {
+			return info.Size()
+		}
+	case interface{ Len() int }:
+		return int64(v.Len())
+	}
+	return -1
+}
+
+// A reader counting the bytes read from the underlying reader.
+type countingReader struct {
+	reader io.Reader
+	count  atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count.Add(int64(n))
+	return n, err
+}
+
+// Keeps track of when the stages started and sends progress updates to the reporter.
+// A nil tracker does nothing.
+type progressTracker struct {
+	reporter ProgressReporter
+	sizes    [2]int64
+	counters [2]*countingReader // The raw bytes read of files whose data is transcoded.
+
+	mu     sync.Mutex
+	starts map[string]time.Time
+}
+
+// Returns a tracker reporting to reporter, or nil if reporter is nil.
+func newProgressTracker(reporter ProgressReporter, src1, src2 io.Reader) *progressTracker {
+	if reporter == nil {
+		return nil
+	}
+	return &progressTracker{
+		reporter: reporter,
+		sizes:    [2]int64{sourceSize(src1), sourceSize(src2)},
+		starts:   make(map[string]time.Time),
+	}
+}
+
+// Sends a progress update with the fraction of the stage done, or -1 if unknown.
+func (t *progressTracker) report(stage string, file int, rows int64, fraction float64) {
+	if t == nil {
+		return
+	}
+
+	now := time.Now()
+	t.mu.Lock()
+	start, exists := t.starts[stage]
+	if !exists {
+		start = now
+		t.starts[stage] = start
+	}
+	t.mu.Unlock()
+
+	elapsed := now.Sub(start)
+	eta := time.Duration(-1)
+	if fraction > 0 {
+		eta = time.Duration(float64(elapsed) * (1 - fraction) / fraction)
+	}
+
+	t.reporter.ReportProgress(Progress{
+		Stage:    stage,
+		File:     file,
+		Rows:     rows,
+		Fraction: fraction,
+		Elapsed:  elapsed,
+		ETA:      eta,
+	})
+}
+
+// Returns a reader of src counting the bytes read from it, for measuring the progress
+// of a file whose data is transcoded before it is parsed. The offsets of the parsed data
+// are then in transcoded bytes, which do not add up to the size of the file.
+func (t *progressTracker) countRawBytes(file int, src io.Reader) io.Reader {
+	if t == nil {
+		return src
+	}
+	counter := &countingReader{reader: src}
+	t.counters[file-1] = counter
+	return counter
+}
+
+// Sends a progress update for reading the file given the bytes read so far. The
+// raw bytes read are used instead of the offset for files whose data is transcoded.
+func (t *progressTracker) reportReading(file int, rows int64, offset int64) {
+	if t == nil {
+		return
+	}
+
+	if counter := t.counters[file-1]; counter != nil {
+		offset = counter.count.Load()
+	}
+	fraction := -1.0
+	if size := t.sizes[file-1]; size > 0 {
+		fraction = min(1, float64(offset)/float64(size))
+	}
+	t.report(ProgressStageReading, file, rows, fraction)
+}
+
+// Sends a progress update for hashing the rows of the file.
+func (t *progressTracker) reportHashing(file int, rows int64, total int64) {
+	if t == nil {
+		return
+	}
+
+	fraction := 1.0
+	if total > 0 {
+		fraction = float64(rows) / float64(total)
+	}
+	t.report(ProgressStageHashing, file, rows, fraction)
+}
+
+// A progress reporter drawing a progress bar on a single line of a terminal.
+type ProgressBar struct {
+	writer io.Writer
+
+	mu     sync.Mutex
+	drawn  bool
+	latest [2]Progress
+}
+
+// Returns a progress bar drawing on writer, usually os.Stderr.
+func NewProgressBar(writer io.Writer) *ProgressBar {
+	return &ProgressBar{writer: writer}
+}
+
+// Returns the progress formatted for the progress bar.
+func formatProgress(progress Progress) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s file %d: ", progress.Stage, progress.File)
+	if progress.Fraction >= 0 {
+		filled := int(progress.Fraction * progressBarWidth)
+		fmt.Fprintf(&builder, "[%s%s] %3.0f%% ", strings.Repeat("#", filled), strings.Repeat(" ", progressBarWidth-filled), progress.Fraction*100)
+	}
+	fmt.Fprintf(&builder, "%s rows", formatThousands(int(progress.Rows)))
+	if progress.ETA >= 0 {
+		fmt.Fprintf(&builder, ", ETA %s", progress.ETA.Round(time.Second))
+	}
+	return builder.String()
+}
+
+func (b *ProgressBar) ReportProgress(progress Progress) {
+	if progress.File < 1 || progress.File > 2 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.latest[progress.File-1] = progress
+	parts := []string{}
+	for _, latest := range b.latest {
+		if latest.Stage != "" {
+			parts = append(parts, formatProgress(latest))
+		}
+	}
+	fmt.Fprintf(b.writer, "\r\033[K%s", strings.Join(parts, " | "))
+	b.drawn = true
+}
+
+// Ends the line of the progress bar if anything was drawn.
+func (b *ProgressBar) Finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.drawn {
+		fmt.Fprintln(b.writer)
+		b.drawn = false
+	}
+}
@@ -0,0 +1,123 @@
+package csvcheckcli_test
+
+import (
+	"bytes"
+	"context"
+	"csvcheckcli/csvcheckcli"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns csv data with a header and the given number of rows numbered from 1,
+// zero-padded so that the rows are sorted.
+func getNumberedCsvString(rows int) string {
+	var builder strings.Builder
+	builder.WriteString("a,b\n")
+	for i := 1; i <= rows; i++ {
+		fmt.Fprintf(&builder, "%06d,%d\n", i, i*2)
+	}
+	return builder.String()
+}
+
+func TestCompareCancelledReturnsPartialResult(t *testing.T) {
+	for i, method := range []string{
+		csvcheckcli.MethodStringSet,
+		csvcheckcli.MethodStringSorted,
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		cfg := csvcheckcli.NewConfig(
+			csvcheckcli.WithMethod(method),
+			csvcheckcli.WithFunction(csvcheckcli.FunctionStringCommon),
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		csvString := getNumberedCsvString(50000)
+		result, err := csvcheckcli.Compare(ctx, strings.NewReader(csvString), strings.NewReader(csvString), cfg)
+
+		assert.ErrorIs(t, err, context.Canceled, indexString)
+		assert.NotNil(t, result, indexString)
+		assert.True(t, result.Partial, indexString)
+		assert.Greater(t, len(result.Rows1), 1, indexString)
+		assert.Less(t, len(result.Rows1), 50001, indexString)
+		assert.Equal(t, result.Rows1, result.Rows2, indexString)
+	}
+}
+
+func TestCompareReportsProgress(t *testing.T) {
+	for i, workers := range []int{1, 2} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+
+		var mu sync.Mutex
+		latest := map[string]csvcheckcli.Progress{}
+		cfg := csvcheckcli.NewConfig(
+			csvcheckcli.WithFunction(csvcheckcli.FunctionStringDifferent),
+			csvcheckcli.WithWorkers(workers),
+			csvcheckcli.WithProgressReporter(csvcheckcli.ProgressFunc(func(progress csvcheckcli.Progress) {
+				mu.Lock()
+				defer mu.Unlock()
+				latest[fmt.Sprintf("%s %d", progress.Stage, progress.File)] = progress
+			})),
+		)
+
+		result, err := csvcheckcli.Compare(context.Background(), strings.NewReader(getNumberedCsvString(25000)), strings.NewReader(getNumberedCsvString(20000)), cfg)
+
+		assert.Nil(t, err, indexString)
+		assert.False(t, result.Partial, indexString)
+		assert.Equal(t, 5001, len(result.Rows1), indexString)
+		for _, key := range []string{"reading 1", "reading 2", "hashing 1", "hashing 2"} {
+			assert.Contains(t, latest, key, indexString)
+			assert.Equal(t, 1.0, latest[key].Fraction, indexString)
+		}
+		assert.Equal(t, int64(25001), latest["reading 1"].Rows, indexString)
+		assert.Equal(t, int64(20001), latest["reading 2"].Rows, indexString)
+	}
+}
+
+func TestCompareReportsProgressOfTranscodedFiles(t *testing.T) {
+	var mu sync.Mutex
+	latest := map[int]csvcheckcli.Progress{}
+	cfg := csvcheckcli.NewConfig(
+		csvcheckcli.WithFunction(csvcheckcli.FunctionStringDifferent),
+		csvcheckcli.WithProgressReporter(csvcheckcli.ProgressFunc(func(progress csvcheckcli.Progress) {
+			mu.Lock()
+			defer mu.Unlock()
+			if progress.Stage == csvcheckcli.ProgressStageReading {
+				assert.LessOrEqual(t, progress.Fraction, 1.0)
+				latest[progress.File] = progress
+			}
+		})),
+	)
+	cfg.Encoding1 = csvcheckcli.EncodingUTF16LE
+	cfg.Encoding2 = csvcheckcli.EncodingAuto
+
+	csvString, err := csvcheckcli.EncodeString(getNumberedCsvString(25000), csvcheckcli.EncodingUTF16LE)
+	assert.Nil(t, err)
+	_, err = csvcheckcli.Compare(context.Background(), strings.NewReader(csvString), strings.NewReader(getNumberedCsvString(20000)), cfg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1.0, latest[1].Fraction)
+	assert.Equal(t, 1.0, latest[2].Fraction)
+}
+
+func TestProgressBar(t *testing.T) {
+	var buffer bytes.Buffer
+	bar := csvcheckcli.NewProgressBar(&buffer)
+
+	bar.Finish()
+	assert.Equal(t, "", buffer.String())
+
+	bar.ReportProgress(csvcheckcli.Progress{Stage: csvcheckcli.ProgressStageReading, File: 1, Rows: 12000, Fraction: 0.5, ETA: -1})
+	bar.ReportProgress(csvcheckcli.Progress{Stage: csvcheckcli.ProgressStageHashing, File: 2, Rows: 3000, Fraction: -1, ETA: -1})
+	bar.Finish()
+
+	lines := strings.Split(buffer.String(), "\r\033[K")
+	assert.Equal(t, 3, len(lines))
+	assert.Equal(t, "reading file 1: [##########          ]  50% 12,000 rows", lines[1])
+	assert.Equal(t, "reading file 1: [##########          ]  50% 12,000 rows | hashing file 2: 3,000 rows\n", lines[2])
+}
@@ -0,0 +1,350 @@
+package csvcheckcli
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+	"github.com/cespare/xxhash"
+)
+
+// The checks of the reconciliation array.
+const (
+	ReconcileCheckRows     = "rows"
+	ReconcileCheckChecksum = "checksum"
+	ReconcileCheckPresent  = "present"
+	ReconcileCheckNulls    = "nulls"
+	ReconcileCheckSum      = "sum"
+)
+
+// The columns of the reconciliation array.
+var reconciliationColumns = []string{"check", "column", "value1", "value2", "match"}
+
+// For holding how a column of the csv files reconciles.
+type ColumnReconciliation struct {
+	Column string   `json:"column"`
+	In1    bool     `json:"in1"` // Whether the first csv has the column.
+	In2    bool     `json:"in2"`
+	Nulls1 int      `json:"nulls1"` // The number of empty values in the first csv.
+	Nulls2 int      `json:"nulls2"`
+	Sum1   *float64 `json:"sum1"` // The sum of the values in the first csv, nil if they are not all numbers.
+	Sum2   *float64 `json:"sum2"`
+	// Whether both sums are missing or they differ by at most the tolerance.
+	SumMatching bool `json:"sum_matching"`
+}
+
+// For holding the row counts, checksums and column reconciliations of two csv files.
+type Reconciliation struct {
+	Rows1           int                    `json:"rows1"` // The number of rows of the first csv, without the columns row.
+	Rows2           int                    `json:"rows2"`
+	Checksum1       string                 `json:"checksum1"` // The order-independent checksum of the rows of the first csv.
+	Checksum2       string                 `json:"checksum2"`
+	ChecksumColumns []string               `json:"checksum_columns"` // The columns of both csv files the checksums are computed over.
+	Columns         []ColumnReconciliation `json:"columns"`
+}
+
+// Returns the number of checks that do not match: the row counts, the checksums and
+// for each column whether both files have it, its empty values and its sums.
+func (r *Reconciliation) Mismatches() int {
+	res := 0
+	for _, matching := range []bool{r.Rows1 == r.Rows2, r.Checksum1 == r.Checksum2} {
+		if !matching {
+			res++
+		}
+	}
+	for _, column := range r.Columns {
+		switch {
+		case !column.In1 || !column.In2:
+			res++
+		default:
+			if column.Nulls1 != column.Nulls2 {
+				res++
+			}
+			if !column.SumMatching {
+				res++
+			}
+		}
+	}
+	return res
+}
+
+// Returns true iff the row counts, checksums and columns of the csv files match.
+func (r *Reconciliation) Matches() bool {
+	return r.Mismatches() == 0
+}
+
+// For holding the totals of a csv file as its rows are read.
+type fileTotals struct {
+	rows     int
+	nulls    []int
+	numbers  []int // The number of non-empty values of each column.
+	numeric  []bool
+	sums     []compensatedSum // Kept compensated so that the sums do not depend on the order of the rows.
+	checksum uint64
+}
+
+// Returns the first record of the csv data, which is its header unless it has none.
+func readFirstRecord(reader *csv.Reader) ([]string, error) {
+	first, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("empty array")
+	}
+	return first, err
+}
+
+// Reads the remaining rows of the csv data and adds them to the totals of the columns
+// at the indices. If the context is cancelled, the totals of the rows read so far are
+// returned together with the context's error. The checksum is the sum of the hashes of the names and values of the
+// checksum columns of each row, which does not depend on the order of the rows.
+func readFileTotals(ctx context.Context, reader *csv.Reader, pending []string, header []csvcheck.StringHashable, indices, checksumIndices []int, normalizer func(string) string, file int, tracker *progressTracker) (*fileTotals, error) {
+	totals := &fileTotals{
+		nulls:   make([]int, len(indices)),
+		numbers: make([]int, len(indices)),
+		numeric: make([]bool, len(indices)),
+		sums:    make([]compensatedSum, len(indices)),
+	}
+	for c := range totals.numeric {
+		totals.numeric[c] = true
+	}
+
+	var buffer []byte
+	var interruption error
+	for {
+		record := pending
+		pending = nil
+		if record == nil {
+			var err error
+			record, err = reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if normalizer != nil {
+			for i, value := range record {
+				record[i] = normalizer(value)
+			}
+		}
+		totals.rows++
+
+		for c, i := range indices {
+			value := record[i]
+			if strings.TrimSpace(value) == "" {
+				totals.nulls[c]++
+				continue
+			}
+			totals.numbers[c]++
+			if !totals.numeric[c] {
+				continue
+			}
+			number, isNumber := parseNumber(value)
+			if !isNumber {
+				totals.numeric[c] = false
+				continue
+			}
+			totals.sums[c].add(number)
+		}
+
+		buffer = buffer[:0]
+		for _, i := range checksumIndices {
+			buffer = appendLengthPrefixed(buffer, header[i].StringHash(), record[i])
+		}
+		totals.checksum += xxhash.Sum64(buffer)
+
+		if totals.rows%progressInterval == 0 {
+			tracker.reportReading(file, int64(totals.rows), reader.InputOffset())
+			if interruption = ctx.Err(); interruption != nil {
+				break
+			}
+		}
+	}
+	tracker.reportReading(file, int64(totals.rows), reader.InputOffset())
+	return totals, interruption
+}
+
+// Returns the sum of the column if all its non-empty values are numbers and it has some.
+func (t *fileTotals) getSum(c int) *float64 {
+	if !t.numeric[c] || t.numbers[c] == 0 {
+		return nil
+	}
+	sum := t.sums[c].value()
+	return &sum
+}
+
+// Reconciles two csv files in a single pass over each, without keeping their rows.
+// If the context is cancelled, the reconciliation of the rows read so far is returned
+// together with the context's error. See Reconcile.
+func reconcileCsv(ctx context.Context, reader1, reader2 *csv.Reader, cfg Config, tracker *progressTracker) (*Reconciliation, error) {
+	first1, err := readFirstRecord(reader1)
+	if err != nil {
+		return nil, err
+	}
+	first2, err := readFirstRecord(reader2)
+	if err != nil {
+		return nil, err
+	}
+	headerArray1, headerArray2, err := prepareCsvArrays([][]csvcheck.StringHashable{csvcheck.GetRowFromRow(first1)}, [][]csvcheck.StringHashable{csvcheck.GetRowFromRow(first2)}, cfg)
+	if err != nil {
+		return nil, err
+	}
+	header1, header2 := headerArray1[0], headerArray2[0]
+	// Without a header, the first record is the first row.
+	var pending1, pending2 []string
+	if cfg.NoHeader1 {
+		pending1 = first1
+	}
+	if cfg.NoHeader2 {
+		pending2 = first2
+	}
+
+	columns, err := resolveInputColumns(header1, header2, cfg)
+	if err != nil {
+		return nil, err
+	}
+	normalizer, err := newValueNormalizer(cfg.UnicodeNormalize, cfg.StripAccents)
+	if err != nil {
+		return nil, err
+	}
+
+	indices1 := getComparedIndices(header1, columns.toUse, columns.toIgnore)
+	indices2 := getComparedIndices(header2, columns.toUse, columns.toIgnore)
+	positions1 := make(map[string]int)
+	for c, i := range indices1 {
+		positions1[header1[i].StringHash()] = c
+	}
+	positions2 := make(map[string]int)
+	for c, i := range indices2 {
+		positions2[header2[i].StringHash()] = c
+	}
+
+	checksumColumns := []string{}
+	for name := range positions1 {
+		if _, inBoth := positions2[name]; inBoth {
+			checksumColumns = append(checksumColumns, name)
+		}
+	}
+	sort.Strings(checksumColumns)
+	checksumIndices1 := make([]int, len(checksumColumns))
+	checksumIndices2 := make([]int, len(checksumColumns))
+	for k, name := range checksumColumns {
+		checksumIndices1[k] = indices1[positions1[name]]
+		checksumIndices2[k] = indices2[positions2[name]]
+	}
+
+	var totals1, totals2 *fileTotals
+	var err1, err2 error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		totals1, err1 = readFileTotals(ctx, reader1, pending1, header1, indices1, checksumIndices1, normalizer, 1, tracker)
+	}()
+	go func() {
+		defer wg.Done()
+		totals2, err2 = readFileTotals(ctx, reader2, pending2, header2, indices2, checksumIndices2, normalizer, 2, tracker)
+	}()
+	wg.Wait()
+	interruption := ctx.Err()
+	if err := errors.Join(err1, err2); err != nil && (interruption == nil || totals1 == nil || totals2 == nil) {
+		return nil, err
+	}
+
+	res := &Reconciliation{
+		Rows1:           totals1.rows,
+		Rows2:           totals2.rows,
+		Checksum1:       fmt.Sprintf("%016x", totals1.checksum),
+		Checksum2:       fmt.Sprintf("%016x", totals2.checksum),
+		ChecksumColumns: checksumColumns,
+		Columns:         []ColumnReconciliation{},
+	}
+
+	addColumn := func(name string) {
+		c1, in1 := positions1[name]
+		c2, in2 := positions2[name]
+		column := ColumnReconciliation{Column: name, In1: in1, In2: in2}
+		if in1 {
+			column.Nulls1 = totals1.nulls[c1]
+			column.Sum1 = totals1.getSum(c1)
+		}
+		if in2 {
+			column.Nulls2 = totals2.nulls[c2]
+			column.Sum2 = totals2.getSum(c2)
+		}
+		column.SumMatching = sumsMatch(column.Sum1, column.Sum2, cfg.Tolerance)
+		res.Columns = append(res.Columns, column)
+	}
+	for _, i := range indices1 {
+		addColumn(header1[i].StringHash())
+	}
+	for _, i := range indices2 {
+		if _, in1 := positions1[header2[i].StringHash()]; !in1 {
+			addColumn(header2[i].StringHash())
+		}
+	}
+	return res, interruption
+}
+
+// Returns true iff both sums are missing or they differ by at most the tolerance once
+// rounded like aggregates.
+func sumsMatch(sum1, sum2 *float64, tolerance float64) bool {
+	if sum1 == nil || sum2 == nil {
+		return sum1 == nil && sum2 == nil
+	}
+	return math.Abs(roundAggregate(*sum1)-roundAggregate(*sum2)) <= tolerance
+}
+
+// Reconciles two csv files based off of the config. Each file is read once and only
+// its totals are kept: the number of rows, the number of empty values and the sum of
+// the numeric values of each compared column, and a checksum of the compared columns
+// the files have in common that does not depend on the order of the rows.
+func Reconcile(reader1, reader2 *csv.Reader, cfg Config) (*Reconciliation, error) {
+	return reconcileCsv(context.Background(), reader1, reader2, cfg, nil)
+}
+
+// Returns the checks of the reconciliation as a csv array with the columns row. The
+// sums are only listed for columns that are numeric in one of the files.
+func GetReconciliationArray(r *Reconciliation) [][]csvcheck.StringHashable {
+	formatSum := func(sum *float64) string {
+		if sum == nil {
+			return ""
+		}
+		return formatAggregate(*sum)
+	}
+	row := func(check, column, value1, value2 string, matching bool) []csvcheck.StringHashable {
+		return csvcheck.GetRowFromRow([]string{check, column, value1, value2, strconv.FormatBool(matching)})
+	}
+
+	res := [][]csvcheck.StringHashable{csvcheck.GetRowFromRow(reconciliationColumns)}
+	res = append(res, row(ReconcileCheckRows, "", strconv.Itoa(r.Rows1), strconv.Itoa(r.Rows2), r.Rows1 == r.Rows2))
+	res = append(res, row(ReconcileCheckChecksum, "", r.Checksum1, r.Checksum2, r.Checksum1 == r.Checksum2))
+	for _, column := range r.Columns {
+		if !column.In1 || !column.In2 {
+			res = append(res, row(ReconcileCheckPresent, column.Column, strconv.FormatBool(column.In1), strconv.FormatBool(column.In2), false))
+			continue
+		}
+		res = append(res, row(ReconcileCheckNulls, column.Column, strconv.Itoa(column.Nulls1), strconv.Itoa(column.Nulls2), column.Nulls1 == column.Nulls2))
+		if column.Sum1 != nil || column.Sum2 != nil {
+			res = append(res, row(ReconcileCheckSum, column.Column, formatSum(column.Sum1), formatSum(column.Sum2), column.SumMatching))
+		}
+	}
+	return res
+}
+
+// Returns lines summarizing the row counts and whether the files reconcile.
+func FormatReconciliationSummary(r *Reconciliation) string {
+	res := fmt.Sprintf("Rows in the first file: %s, in the second file: %s\n", formatThousands(r.Rows1), formatThousands(r.Rows2))
+	if r.Matches() {
+		return res + "The files reconcile.\n"
+	}
+	return res + fmt.Sprintf("Mismatching checks: %s\n", formatThousands(r.Mismatches()))
+}
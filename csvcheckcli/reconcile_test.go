@@ -0,0 +1,157 @@
+package csvcheckcli_test
+
+import (
+	"context"
+	"csvcheckcli/csvcheckcli"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcile(t *testing.T) {
+	csvString1 := "id,amount,note\n1,10.10,x\n2,20.20,\n3,5,y\n"
+
+	for i, data := range []struct {
+		csvString1  string
+		csvString2  string
+		options     []csvcheckcli.Option
+		tolerance   float64
+		mismatches  int
+		expectError bool
+	}{
+		{
+			csvString2: "id,amount,note\n3,5,y\n1,10.10,x\n2,20.20,\n",
+			mismatches: 0,
+		},
+		{
+			csvString2: "id,amount,note\n1,10.10,x\n2,20.21,\n3,5,y\n",
+			mismatches: 2,
+		},
+		{
+			csvString2: "id,amount,note\n1,10.10,x\n2,20.21,\n3,5,y\n",
+			tolerance:  0.02,
+			mismatches: 1,
+		},
+		{
+			csvString2: "id,amount,note\n1,10.10,x\n2,20.20,\n",
+			mismatches: 4,
+		},
+		{
+			csvString2: "id,amount,note\n1,10.10,x\n2,20.20,z\n3,5,y\n",
+			mismatches: 2,
+		},
+		{
+			csvString2: "id,amount,extra\n3,5,q\n2,20.20,q\n1,10.10,q\n",
+			mismatches: 2,
+		},
+		{
+			csvString2: "id,amount,note\n1,10.10,z\n2,20.20,z\n3,5,z\n",
+			options:    []csvcheckcli.Option{csvcheckcli.WithColumnsToUse("id", "amount")},
+			mismatches: 0,
+		},
+		{
+			csvString1: "1,2\n3,4\n",
+			csvString2: "3,4\n1,2\n",
+			options:    []csvcheckcli.Option{csvcheckcli.WithNoHeader(true, true)},
+			mismatches: 0,
+		},
+		{
+			csvString2:  "id,amount,note\n1,10.10\n",
+			expectError: true,
+		},
+		{
+			csvString2:  "",
+			expectError: true,
+		},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		if data.csvString1 == "" {
+			data.csvString1 = csvString1
+		}
+		cfg := csvcheckcli.NewConfig(append([]csvcheckcli.Option{csvcheckcli.WithFunction(csvcheckcli.FunctionStringReconcile)}, data.options...)...)
+		cfg.Tolerance = data.tolerance
+
+		r, err := csvcheckcli.Reconcile(csv.NewReader(strings.NewReader(data.csvString1)), csv.NewReader(strings.NewReader(data.csvString2)), cfg)
+
+		if data.expectError {
+			assert.NotNil(t, err, indexString)
+			continue
+		}
+		assert.Nil(t, err, indexString)
+		assert.Equal(t, data.mismatches, r.Mismatches(), indexString)
+		assert.Equal(t, data.mismatches == 0, r.Matches(), indexString)
+	}
+}
+
+func TestReconcileTotals(t *testing.T) {
+	cfg := csvcheckcli.NewConfig(csvcheckcli.WithFunction(csvcheckcli.FunctionStringReconcile))
+	r, err := csvcheckcli.Reconcile(
+		csv.NewReader(strings.NewReader("id,amount,note\n1,0.1,x\n2,0.2,\n3,,y\n")),
+		csv.NewReader(strings.NewReader("id,note,amount\n1,,0.3\n")),
+		cfg,
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, r.Rows1)
+	assert.Equal(t, 1, r.Rows2)
+	assert.Equal(t, []string{"amount", "id", "note"}, r.ChecksumColumns)
+	assert.Equal(t, 3, len(r.Columns))
+
+	amount := r.Columns[1]
+	assert.Equal(t, "amount", amount.Column)
+	assert.Equal(t, 1, amount.Nulls1)
+	assert.Equal(t, 0, amount.Nulls2)
+	assert.InDelta(t, 0.3, *amount.Sum1, 1e-12)
+	assert.InDelta(t, 0.3, *amount.Sum2, 1e-12)
+	assert.True(t, amount.SumMatching)
+
+	note := r.Columns[2]
+	assert.Nil(t, note.Sum1)
+	assert.Nil(t, note.Sum2)
+	assert.True(t, note.SumMatching)
+}
+
+func TestGetReconciliationArray(t *testing.T) {
+	sum1, sum2 := 35.3, 35.31
+	r := &csvcheckcli.Reconciliation{
+		Rows1:     3,
+		Rows2:     3,
+		Checksum1: "00000000000000aa",
+		Checksum2: "00000000000000bb",
+		Columns: []csvcheckcli.ColumnReconciliation{
+			{Column: "amount", In1: true, In2: true, Sum1: &sum1, Sum2: &sum2},
+			{Column: "note", In1: true, In2: true, Nulls1: 1, Nulls2: 1, SumMatching: true},
+			{Column: "extra", In2: true, SumMatching: true},
+		},
+	}
+
+	expected := `
+check,column,value1,value2,match
+rows,,3,3,true
+checksum,,00000000000000aa,00000000000000bb,false
+nulls,amount,0,0,true
+sum,amount,35.3,35.31,false
+nulls,note,1,1,true
+present,extra,false,true,false
+`
+	assert.Equal(t, Get2DArrayFromCsvString(expected), csvcheckcli.GetReconciliationArray(r))
+	assert.Equal(t, 3, r.Mismatches())
+	assert.Equal(t, "Rows in the first file: 3, in the second file: 3\nMismatching checks: 3\n", csvcheckcli.FormatReconciliationSummary(r))
+}
+
+func TestCompareReconcile(t *testing.T) {
+	cfg := csvcheckcli.NewConfig(csvcheckcli.WithFunction(csvcheckcli.FunctionStringReconcile))
+	result, err := csvcheckcli.Compare(context.Background(), strings.NewReader("a,b\n1,2\n3,4\n"), strings.NewReader("b,a\n4,3\n2,1\n"), cfg)
+
+	assert.Nil(t, err)
+	assert.False(t, result.Partial)
+	assert.Nil(t, result.Rows1)
+	assert.True(t, result.Reconciliation.Matches())
+
+	cfg.Combine = true
+	_, err = csvcheckcli.Compare(context.Background(), strings.NewReader("a\n1\n"), strings.NewReader("a\n1\n"), cfg)
+	assert.NotNil(t, err)
+}
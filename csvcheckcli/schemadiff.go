@@ -0,0 +1,219 @@
+package csvcheckcli
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+)
+
+// The kinds of column changes reported by the schemadiff function.
+const (
+	SchemaChangeAdded     = "added"
+	SchemaChangeRemoved   = "removed"
+	SchemaChangeRenamed   = "renamed"
+	SchemaChangeReordered = "reordered"
+)
+
+// The columns of the schema diff array.
+var schemaDiffColumns = []string{"change", "column", "new_column", "position1", "position2"}
+
+// For holding a change of a column between the headers of two csv arrays.
+type ColumnChange struct {
+	Change    string `json:"change"`     // One of the SchemaChange constants.
+	Column    string `json:"column"`     // The name in the first csv, or in the second one for added columns.
+	NewColumn string `json:"new_column"` // The name in the second csv for renamed columns.
+	Position1 int    `json:"position1"`  // The 1-based position in the first csv, or 0 for added columns.
+	Position2 int    `json:"position2"`  // The 1-based position in the second csv, or 0 for removed columns.
+}
+
+// For holding the differences between the columns and row counts of two csv arrays.
+type SchemaDiff struct {
+	Columns1 []string       `json:"columns1"`
+	Columns2 []string       `json:"columns2"`
+	Rows1    int            `json:"rows1"` // The number of rows of the first csv, without the columns row.
+	Rows2    int            `json:"rows2"`
+	Changes  []ColumnChange `json:"changes"`
+}
+
+// Returns true iff a column was removed or renamed, which breaks readers
+// looking up columns by name.
+func (d *SchemaDiff) IsBreaking() bool {
+	for _, change := range d.Changes {
+		if change.Change == SchemaChangeRemoved || change.Change == SchemaChangeRenamed {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the positions in a of the elements of the longest common subsequence of a and b.
+func longestCommonSubsequence(a, b []string) map[int]bool {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	res := make(map[int]bool)
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			res[i] = true
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return res
+}
+
+// Returns the differences between the columns and row counts of the csv arrays.
+// Columns only in one of them that closely resemble each other are reported as
+// renamed, and columns in both that moved relative to the others as reordered.
+func GetSchemaDiff(csvArray1, csvArray2 [][]csvcheck.StringHashable, cfg Config) (*SchemaDiff, error) {
+	csvArray1, csvArray2, err := prepareCsvArrays(csvArray1, csvArray2, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &SchemaDiff{
+		Columns1: getRowStrings(csvArray1[0]),
+		Columns2: getRowStrings(csvArray2[0]),
+		Rows1:    len(csvArray1) - 1,
+		Rows2:    len(csvArray2) - 1,
+		Changes:  []ColumnChange{},
+	}
+
+	positions1 := make(map[string]int)
+	for i, column := range diff.Columns1 {
+		positions1[column] = i + 1
+	}
+	positions2 := make(map[string]int)
+	for i, column := range diff.Columns2 {
+		positions2[column] = i + 1
+	}
+
+	common1 := []string{}
+	removed := []string{}
+	for _, column := range diff.Columns1 {
+		if positions2[column] > 0 {
+			common1 = append(common1, column)
+		} else {
+			removed = append(removed, column)
+		}
+	}
+	common2 := []string{}
+	added := []string{}
+	for _, column := range diff.Columns2 {
+		if positions1[column] > 0 {
+			common2 = append(common2, column)
+		} else {
+			added = append(added, column)
+		}
+	}
+
+	type candidate struct {
+		from, to string
+		distance int
+	}
+	candidates := []candidate{}
+	for _, from := range removed {
+		for _, to := range added {
+			if distance, similar := headerSimilarity(from, to); similar {
+				candidates = append(candidates, candidate{from, to, distance})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+	renamedTo := make(map[string]string)
+	renamedFrom := make(map[string]bool)
+	for _, c := range candidates {
+		if _, exists := renamedTo[c.from]; !exists && !renamedFrom[c.to] {
+			renamedTo[c.from] = c.to
+			renamedFrom[c.to] = true
+		}
+	}
+
+	inOrder := longestCommonSubsequence(common1, common2)
+	for i, column := range common1 {
+		if !inOrder[i] {
+			diff.Changes = append(diff.Changes, ColumnChange{Change: SchemaChangeReordered, Column: column, Position1: positions1[column], Position2: positions2[column]})
+		}
+	}
+	for _, column := range removed {
+		if to, exists := renamedTo[column]; exists {
+			diff.Changes = append(diff.Changes, ColumnChange{Change: SchemaChangeRenamed, Column: column, NewColumn: to, Position1: positions1[column], Position2: positions2[to]})
+		} else {
+			diff.Changes = append(diff.Changes, ColumnChange{Change: SchemaChangeRemoved, Column: column, Position1: positions1[column]})
+		}
+	}
+	for _, column := range added {
+		if !renamedFrom[column] {
+			diff.Changes = append(diff.Changes, ColumnChange{Change: SchemaChangeAdded, Column: column, Position2: positions2[column]})
+		}
+	}
+	return diff, nil
+}
+
+// Returns the column changes as a csv array with the columns row. Positions that
+// do not apply are left empty.
+func GetSchemaDiffArray(diff *SchemaDiff) [][]csvcheck.StringHashable {
+	formatPosition := func(position int) string {
+		if position == 0 {
+			return ""
+		}
+		return strconv.Itoa(position)
+	}
+
+	res := [][]csvcheck.StringHashable{csvcheck.GetRowFromRow(schemaDiffColumns)}
+	for _, change := range diff.Changes {
+		res = append(res, csvcheck.GetRowFromRow([]string{
+			change.Change,
+			change.Column,
+			change.NewColumn,
+			formatPosition(change.Position1),
+			formatPosition(change.Position2),
+		}))
+	}
+	return res
+}
+
+// Returns true iff the csv arrays have different numbers of rows.
+func (d *SchemaDiff) RowCountChanged() bool {
+	return d.Rows1 != d.Rows2
+}
+
+// Returns lines summarizing the row counts, whether they changed and whether the
+// changes are breaking.
+func FormatSchemaDiffSummary(diff *SchemaDiff) string {
+	res := fmt.Sprintf("Rows in the first file: %s, in the second file: %s\n", formatThousands(diff.Rows1), formatThousands(diff.Rows2))
+	if diff.RowCountChanged() {
+		sign := "+"
+		if diff.Rows2 < diff.Rows1 {
+			sign = "-"
+		}
+		res += fmt.Sprintf("Row count changed by %s%s.\n", sign, formatThousands(max(diff.Rows1, diff.Rows2)-min(diff.Rows1, diff.Rows2)))
+	}
+	switch {
+	case diff.IsBreaking():
+		res += "Breaking changes: columns were removed or renamed.\n"
+	case len(diff.Changes) == 0:
+		res += "No column changes.\n"
+	}
+	return res
+}
@@ -0,0 +1,79 @@
+package csvcheckcli_test
+
+import (
+	"context"
+	"csvcheckcli/csvcheckcli"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSchemaDiff(t *testing.T) {
+	csvArray1 := Get2DArrayFromCsvString(`
+Customer ID,name,amount,note,fax
+1,a,10,x,1
+`)
+	csvArray2 := Get2DArrayFromCsvString(`
+customer_id,amount,name,note,email
+1,10,a,x,e
+2,20,b,y,f
+`)
+
+	diff, err := csvcheckcli.GetSchemaDiff(csvArray1, csvArray2, csvcheckcli.NewConfig())
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, diff.Rows1)
+	assert.Equal(t, 2, diff.Rows2)
+	assert.True(t, diff.IsBreaking())
+	assert.Equal(t, Get2DArrayFromCsvString(`
+change,column,new_column,position1,position2
+reordered,name,,2,3
+renamed,Customer ID,customer_id,1,1
+removed,fax,,5,
+added,email,,,5
+`), csvcheckcli.GetSchemaDiffArray(diff))
+
+	assert.True(t, diff.RowCountChanged())
+	assert.Equal(t, "Rows in the first file: 1, in the second file: 2\nRow count changed by +1.\nBreaking changes: columns were removed or renamed.\n", csvcheckcli.FormatSchemaDiffSummary(diff))
+
+	diff, err = csvcheckcli.GetSchemaDiff(csvArray1, csvArray2, csvcheckcli.NewConfig(csvcheckcli.WithNormalizeHeaders(true)))
+	assert.Nil(t, err)
+	assert.Equal(t, []csvcheckcli.ColumnChange{
+		{Change: csvcheckcli.SchemaChangeReordered, Column: "name", Position1: 2, Position2: 3},
+		{Change: csvcheckcli.SchemaChangeRemoved, Column: "fax", Position1: 5},
+		{Change: csvcheckcli.SchemaChangeAdded, Column: "email", Position2: 5},
+	}, diff.Changes)
+}
+
+func TestGetSchemaDiffNotBreaking(t *testing.T) {
+	for i, data := range []struct {
+		csvString1      string
+		csvString2      string
+		changes         int
+		rowCountChanged bool
+	}{
+		{csvString1: "a,b\n1,2\n", csvString2: "a,b\n1,2\n3,4\n", changes: 0, rowCountChanged: true},
+		{csvString1: "a,b\n1,2\n", csvString2: "b,a,c\n2,1,3\n", changes: 2},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		diff, err := csvcheckcli.GetSchemaDiff(Get2DArrayFromCsvString(data.csvString1), Get2DArrayFromCsvString(data.csvString2), csvcheckcli.NewConfig())
+		assert.Nil(t, err, indexString)
+		assert.False(t, diff.IsBreaking(), indexString)
+		assert.Equal(t, data.changes, len(diff.Changes), indexString)
+		assert.Equal(t, data.rowCountChanged, diff.RowCountChanged(), indexString)
+	}
+}
+
+func TestCompareSchemaDiff(t *testing.T) {
+	cfg := csvcheckcli.NewConfig(csvcheckcli.WithFunction(csvcheckcli.FunctionStringSchemaDiff))
+	result, err := csvcheckcli.Compare(context.Background(), strings.NewReader("a,b\n1,2\n"), strings.NewReader("a\n1\n"), cfg)
+
+	assert.Nil(t, err)
+	assert.True(t, result.SchemaDiff.IsBreaking())
+
+	cfg.Method = csvcheckcli.MethodStringSorted
+	_, err = csvcheckcli.Compare(context.Background(), strings.NewReader("a,b\n1,2\n"), strings.NewReader("a\n1\n"), cfg)
+	assert.NotNil(t, err)
+}
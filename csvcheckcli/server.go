@@ -0,0 +1,291 @@
+package csvcheckcli
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+	"github.com/spf13/pflag"
+)
+
+// The defaults of the serve command.
+const (
+	DefaultServeAddr           = ":8080"
+	DefaultServeMaxRequestSize = 64 << 20
+	DefaultServeTimeout        = time.Minute
+)
+
+// The size of uploaded files above which they are kept on disk while being compared.
+const multipartMemory = 32 << 20
+
+// The options of the compare endpoint that only make sense on the command line.
+var serverUnsupportedOptions = []string{
+	"inputdir", "files", "outputdir", "addtimestamp", "overwrite", "no-clobber", "filemode", "outputname",
+	"progress", "csv", "json", "markdown", "prettyformatmaxlength", "limit", "head", "tail", "sample", "seed", "combine", "tui", "watch", "watchinterval", "outputencoding",
+}
+
+// For holding the options of the serve command.
+type ServeConfig struct {
+	Addr           string
+	MaxRequestSize int64         // The maximum size of a request in bytes, including the uploaded files.
+	Timeout        time.Duration // The maximum time for reading a request and for comparing the files.
+}
+
+// Checks if the serve options are valid.
+func (c ServeConfig) Validate() error {
+	if c.Addr == "" {
+		return fmt.Errorf("addr must be given")
+	}
+	if c.MaxRequestSize <= 0 {
+		return fmt.Errorf("maxrequestsize must be positive")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	return nil
+}
+
+// For holding the json response of the compare endpoint. Only the fields of the
+// function used are set.
+type CompareResponse struct {
+	Columns1       []string         `json:"columns1"`
+	Rows1          [][]string       `json:"rows1"`
+	Columns2       []string         `json:"columns2"`
+	Rows2          [][]string       `json:"rows2"`
+	Stats          *DifferenceStats `json:"stats,omitempty"`
+	SchemaDiff     *SchemaDiff      `json:"schemadiff,omitempty"`
+	Reconciliation *Reconciliation  `json:"reconciliation,omitempty"`
+}
+
+// Returns the handler of the http api:
+//
+//	GET /healthz answers ok.
+//	POST /compare compares the files uploaded as the file1 and file2 fields of a
+//	multipart form. The options field holds the comparison options as the json of a
+//	UserInput, and the format field is json (the default) or csv. In csv, the
+//	results of both files are returned as a single table like with --combine.
+func NewServerHandler(cfg ServeConfig) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("POST /compare", func(w http.ResponseWriter, r *http.Request) {
+		handleCompare(w, r, cfg)
+	})
+	return mux
+}
+
+// Returns a server of the http api listening on the address of the config.
+func NewServer(cfg ServeConfig) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           NewServerHandler(cfg),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       cfg.Timeout,
+		// Reading the request and comparing the files may each take up to the timeout.
+		WriteTimeout: 2*cfg.Timeout + 10*time.Second,
+		IdleTimeout:  2 * time.Minute,
+	}
+}
+
+// The status code of requests whose client went away before the comparison finished.
+const statusClientClosedRequest = 499
+
+// Writes the error as json with the status code.
+func writeServerError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// Returns the comparison options of the options field of a compare request. Options
+// not given keep the defaults of the command-line flags.
+func parseServerOptions(options string) (Config, error) {
+	cfg := NewConfig()
+	if options == "" {
+		return cfg, nil
+	}
+
+	given := make(map[string]json.RawMessage)
+	err := json.Unmarshal([]byte(options), &given)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid options: %w", err)
+	}
+	for _, name := range serverUnsupportedOptions {
+		if _, exists := given[name]; exists {
+			return Config{}, fmt.Errorf("option %s is not supported by the server", name)
+		}
+	}
+
+	// Decoding into the pointers of the user input overwrites the defaults they point to.
+	input := cfg.UserInput()
+	decoder := json.NewDecoder(bytes.NewReader([]byte(options)))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&input)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid options: %w", err)
+	}
+	cfg = input.Config()
+	cfg.Workers = min(cfg.Workers, runtime.NumCPU())
+	return cfg, cfg.Validate()
+}
+
+// Returns the uploaded file of the multipart form field.
+func openUploadedFile(r *http.Request, field string) (multipart.File, string, error) {
+	file, header, err := r.FormFile(field)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s must be uploaded: %w", field, err)
+	}
+	return file, header.Filename, nil
+}
+
+// Returns the status code of an error opening an uploaded file. Only missing files
+// are the fault of the client.
+func getUploadErrorStatus(err error) int {
+	if errors.Is(err, http.ErrMissingFile) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// Handles a request of the compare endpoint.
+func handleCompare(w http.ResponseWriter, r *http.Request, cfg ServeConfig) {
+	r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxRequestSize)
+	err := r.ParseMultipartForm(multipartMemory)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			writeServerError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("the request is larger than %d bytes", cfg.MaxRequestSize))
+		} else {
+			writeServerError(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	format := r.FormValue("format")
+	if format != "" && format != "json" && format != "csv" {
+		writeServerError(w, http.StatusBadRequest, fmt.Errorf("unsupported format %s", format))
+		return
+	}
+	compareCfg, err := parseServerOptions(r.FormValue("options"))
+	if err != nil {
+		writeServerError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	file1, fileName1, err := openUploadedFile(r, "file1")
+	if err != nil {
+		writeServerError(w, getUploadErrorStatus(err), err)
+		return
+	}
+	defer file1.Close()
+	file2, fileName2, err := openUploadedFile(r, "file2")
+	if err != nil {
+		writeServerError(w, getUploadErrorStatus(err), err)
+		return
+	}
+	defer file2.Close()
+
+	ctx, cancel := context.WithTimeout(r.Context(), cfg.Timeout)
+	defer cancel()
+	result, err := Compare(ctx, file1, file2, compareCfg)
+	var readError *ReadError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		writeServerError(w, http.StatusGatewayTimeout, fmt.Errorf("the comparison did not finish within %s", cfg.Timeout))
+		return
+	case errors.Is(err, context.Canceled):
+		writeServerError(w, statusClientClosedRequest, fmt.Errorf("the request was cancelled"))
+		return
+	case errors.As(err, &readError):
+		writeServerError(w, http.StatusInternalServerError, err)
+		return
+	case err != nil:
+		// The remaining errors come from the options or the csv data.
+		writeServerError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if format == "csv" {
+		if fileName1 == fileName2 {
+			fileName1, fileName2 = "file1", "file2"
+		}
+		writeCsvResponse(w, result, fileName1, fileName2)
+		return
+	}
+
+	response := CompareResponse{Stats: result.Stats, SchemaDiff: result.SchemaDiff, Reconciliation: result.Reconciliation}
+	if result.Stats == nil && result.SchemaDiff == nil && result.Reconciliation == nil {
+		response.Columns1, response.Rows1 = getRowStrings(result.Rows1[0]), getRowsStrings(result.Rows1[1:])
+		response.Columns2, response.Rows2 = getRowStrings(result.Rows2[0]), getRowsStrings(result.Rows2[1:])
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Writes the result as a csv table. The rows of both files are combined with
+// their file names in the SourceColumnName column. Values are quoted as needed.
+func writeCsvResponse(w http.ResponseWriter, result *Result, fileName1, fileName2 string) {
+	var arr [][]csvcheck.StringHashable
+	var err error
+	switch {
+	case result.Stats != nil:
+		arr = GetStatsArray(result.Stats)
+	case result.SchemaDiff != nil:
+		arr = GetSchemaDiffArray(result.SchemaDiff)
+	case result.Reconciliation != nil:
+		arr = GetReconciliationArray(result.Reconciliation)
+	default:
+		arr, err = CombineResArrays(result.Rows1, result.Rows2, fileName1, fileName2)
+	}
+	if err != nil {
+		writeServerError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	writer := csv.NewWriter(w)
+	for _, row := range arr {
+		writer.Write(getRowStrings(row))
+	}
+	writer.Flush()
+}
+
+// Returns the values of the rows as strings.
+func getRowsStrings(rows [][]csvcheck.StringHashable) [][]string {
+	res := make([][]string, len(rows))
+	for i, row := range rows {
+		res[i] = getRowStrings(row)
+	}
+	return res
+}
+
+// Parses the arguments of the serve command (without the command name) into a
+// ServeConfig. If help is requested, pflag.ErrHelp is returned.
+func ParseServeArgs(args []string) (ServeConfig, error) {
+	var cfg ServeConfig
+	flags := pflag.NewFlagSet("csvcheckcli serve", pflag.ContinueOnError)
+	flags.StringVar(&cfg.Addr, "addr", DefaultServeAddr, "The address to listen on.")
+	flags.Int64Var(&cfg.MaxRequestSize, "maxrequestsize", DefaultServeMaxRequestSize, "The maximum size of a request in bytes, including the uploaded files.")
+	flags.DurationVar(&cfg.Timeout, "timeout", DefaultServeTimeout, "The maximum time for reading a request and for comparing the files of a request.")
+	err := flags.Parse(args)
+	if err != nil {
+		return ServeConfig{}, err
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return ServeConfig{}, err
+	}
+	return cfg, nil
+}
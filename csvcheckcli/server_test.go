@@ -0,0 +1,199 @@
+package csvcheckcli_test
+
+import (
+	"bytes"
+	"context"
+	"csvcheckcli/csvcheckcli"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(maxRequestSize int64) *httptest.Server {
+	return httptest.NewServer(csvcheckcli.NewServerHandler(csvcheckcli.ServeConfig{
+		Addr:           csvcheckcli.DefaultServeAddr,
+		MaxRequestSize: maxRequestSize,
+		Timeout:        time.Minute,
+	}))
+}
+
+// Posts the fields and the files, given by field name, as a multipart form to the
+// compare endpoint and returns the status code and body of the response.
+func postCompare(t *testing.T, server *httptest.Server, fields map[string]string, files map[string]string) (int, string) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range fields {
+		assert.Nil(t, writer.WriteField(name, value))
+	}
+	for name, content := range files {
+		part, err := writer.CreateFormFile(name, name+".csv")
+		assert.Nil(t, err)
+		_, err = io.WriteString(part, content)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, writer.Close())
+
+	response, err := http.Post(server.URL+"/compare", writer.FormDataContentType(), &body)
+	assert.Nil(t, err)
+	defer response.Body.Close()
+	responseBody, err := io.ReadAll(response.Body)
+	assert.Nil(t, err)
+	return response.StatusCode, string(responseBody)
+}
+
+func TestServerHealthz(t *testing.T) {
+	server := newTestServer(csvcheckcli.DefaultServeMaxRequestSize)
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/healthz")
+	assert.Nil(t, err)
+	defer response.Body.Close()
+	body, _ := io.ReadAll(response.Body)
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "ok\n", string(body))
+}
+
+func TestServerCompareJson(t *testing.T) {
+	server := newTestServer(csvcheckcli.DefaultServeMaxRequestSize)
+	defer server.Close()
+
+	status, body := postCompare(t, server,
+		map[string]string{"options": `{"function": "different", "method": "match", "keepindex": true}`},
+		map[string]string{"file1": "a,b\n1,2\n3,4\n", "file2": "a,b\n1,2\n5,6\n"},
+	)
+
+	assert.Equal(t, http.StatusOK, status)
+	var response csvcheckcli.CompareResponse
+	assert.Nil(t, json.Unmarshal([]byte(body), &response))
+	assert.Equal(t, csvcheckcli.CompareResponse{
+		Columns1: []string{"a", "b", csvcheckcli.IndexColumnName},
+		Rows1:    [][]string{{"3", "4", "2"}},
+		Columns2: []string{"a", "b", csvcheckcli.IndexColumnName},
+		Rows2:    [][]string{{"5", "6", "2"}},
+	}, response)
+}
+
+func TestServerCompareCsv(t *testing.T) {
+	server := newTestServer(csvcheckcli.DefaultServeMaxRequestSize)
+	defer server.Close()
+
+	status, body := postCompare(t, server,
+		map[string]string{"options": `{"function": "different"}`, "format": "csv"},
+		map[string]string{"file1": "a,b\n1,2\n3,4\n", "file2": "a,b\n1,2\n5,6\n"},
+	)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, fmt.Sprintf("%s,a,b\nfile1.csv,3,4\nfile2.csv,5,6\n", csvcheckcli.SourceColumnName), body)
+}
+
+func TestServerCompareCsvQuotedValues(t *testing.T) {
+	server := newTestServer(csvcheckcli.DefaultServeMaxRequestSize)
+	defer server.Close()
+
+	status, body := postCompare(t, server,
+		map[string]string{"options": `{"function": "different"}`, "format": "csv"},
+		map[string]string{"file1": "a,b\n\"Smith, John\",1\n", "file2": "a,b\n\"say \"\"hi\"\"\nagain\",2\n"},
+	)
+
+	assert.Equal(t, http.StatusOK, status)
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{csvcheckcli.SourceColumnName, "a", "b"},
+		{"file1.csv", "Smith, John", "1"},
+		{"file2.csv", "say \"hi\"\nagain", "2"},
+	}, records)
+}
+
+func TestServerCompareStats(t *testing.T) {
+	server := newTestServer(csvcheckcli.DefaultServeMaxRequestSize)
+	defer server.Close()
+
+	status, body := postCompare(t, server,
+		map[string]string{"options": `{"function": "stats", "keycolumns": ["id"]}`},
+		map[string]string{"file1": "id,amount\n1,10\n2,20\n", "file2": "id,amount\n1,10\n2,25\n"},
+	)
+
+	assert.Equal(t, http.StatusOK, status)
+	var response csvcheckcli.CompareResponse
+	assert.Nil(t, json.Unmarshal([]byte(body), &response))
+	assert.Equal(t, 2, response.Stats.Paired)
+	assert.Equal(t, 1, response.Stats.Columns[0].Differing)
+}
+
+func TestServerCompareBadRequests(t *testing.T) {
+	server := newTestServer(1000)
+	defer server.Close()
+
+	files := map[string]string{"file1": "a\n1\n", "file2": "a\n2\n"}
+	for i, data := range []struct {
+		fields         map[string]string
+		files          map[string]string
+		expectedStatus int
+	}{
+		{fields: map[string]string{"options": `{"function": "common"}`}, files: files, expectedStatus: http.StatusOK},
+		{fields: map[string]string{}, files: files, expectedStatus: http.StatusBadRequest},
+		{fields: map[string]string{"options": `{"function": "common"}`}, files: map[string]string{"file1": "a\n1\n"}, expectedStatus: http.StatusBadRequest},
+		{fields: map[string]string{"options": `{"function": "common", "outputdir": "out"}`}, files: files, expectedStatus: http.StatusBadRequest},
+		{fields: map[string]string{"options": `{"function": "common", "nosuchoption": true}`}, files: files, expectedStatus: http.StatusBadRequest},
+		{fields: map[string]string{"options": `{"function": "common"`}, files: files, expectedStatus: http.StatusBadRequest},
+		{fields: map[string]string{"options": `{"function": "common"}`, "format": "xml"}, files: files, expectedStatus: http.StatusBadRequest},
+		{fields: map[string]string{"options": `{"function": "common", "usecolumns": ["b"]}`}, files: files, expectedStatus: http.StatusBadRequest},
+		{fields: map[string]string{"options": `{"function": "common"}`}, files: map[string]string{"file1": strings.Repeat("a\n", 1000), "file2": "a\n"}, expectedStatus: http.StatusRequestEntityTooLarge},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		status, _ := postCompare(t, server, data.fields, data.files)
+		assert.Equal(t, data.expectedStatus, status, indexString)
+	}
+}
+
+func TestServerCompareCancelled(t *testing.T) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, name := range []string{"file1", "file2"} {
+		part, err := writer.CreateFormFile(name, name+".csv")
+		assert.Nil(t, err)
+		io.WriteString(part, "a\n1\n")
+	}
+	writer.WriteField("options", `{"function": "common"}`)
+	assert.Nil(t, writer.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	request := httptest.NewRequest(http.MethodPost, "/compare", &body).WithContext(ctx)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	recorder := httptest.NewRecorder()
+	csvcheckcli.NewServerHandler(csvcheckcli.ServeConfig{MaxRequestSize: csvcheckcli.DefaultServeMaxRequestSize, Timeout: time.Minute}).ServeHTTP(recorder, request)
+
+	assert.Equal(t, 499, recorder.Code)
+}
+
+func TestServerCompareMethodNotAllowed(t *testing.T) {
+	server := newTestServer(csvcheckcli.DefaultServeMaxRequestSize)
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/compare")
+	assert.Nil(t, err)
+	response.Body.Close()
+
+	assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+}
+
+func TestParseServeArgs(t *testing.T) {
+	cfg, err := csvcheckcli.ParseServeArgs([]string{"--addr", ":9090", "--timeout", "5s"})
+	assert.Nil(t, err)
+	assert.Equal(t, csvcheckcli.ServeConfig{Addr: ":9090", MaxRequestSize: csvcheckcli.DefaultServeMaxRequestSize, Timeout: 5 * time.Second}, cfg)
+
+	_, err = csvcheckcli.ParseServeArgs([]string{"--timeout", "0s"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,441 @@
+package csvcheckcli
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+	"github.com/spf13/pflag"
+)
+
+// The names of the files in the directory of a dataset in a snapshot store.
+const (
+	snapshotFilePrefix = "snapshot_"
+	snapshotFileExt    = ".csv"
+	historyFileName    = "history.csv"
+)
+
+// The layout of the times in snapshot ids. Ids are taken in UTC, so that they sort in
+// chronological order even when the local time is turned back.
+const snapshotTimeLayout = "2006_01_02_15_04_05.000000000"
+
+// The columns of the history file.
+var historyColumns = []string{"time", "name", "file", "rows", "baseline", "new", "removed", "changed", "snapshot"}
+
+// For holding the options of the snapshot command.
+type SnapshotConfig struct {
+	InputDir         string
+	File             string
+	KeyColumns       []string
+	ColumnsToUse     []string
+	ColumnsToIgnore  []string
+	NormalizeHeaders bool
+	NoHeader         bool
+	SnapshotDir      string
+	Name             string
+	AgainstBaseline  string
+	Retain           int
+	NoSave           bool
+}
+
+// For holding the options of the history command.
+type HistoryConfig struct {
+	SnapshotDir      string
+	Name             string
+	PrintInCsvFormat bool
+}
+
+// For holding a run of the snapshot command recorded in the history of a dataset.
+type HistoryEntry struct {
+	Time     time.Time
+	Name     string // The dataset the run belongs to.
+	File     string // The file the fingerprints were taken of.
+	Rows     int    // The number of rows of the file.
+	Baseline string // The id of the snapshot compared against, or empty if there was none.
+	New      int
+	Removed  int
+	Changed  int
+	Snapshot string // The id of the snapshot saved, or empty if none was saved.
+}
+
+// A local directory holding the fingerprint snapshots and history of datasets,
+// with one subdirectory per dataset name.
+type SnapshotStore struct {
+	dir string
+}
+
+// Returns a snapshot store in the directory. The directory is created on the first save.
+func NewSnapshotStore(dir string) SnapshotStore {
+	return SnapshotStore{dir: dir}
+}
+
+// Returns an error if the name cannot be used as a dataset name.
+func checkSnapshotName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid snapshot name %q", name)
+	}
+	return nil
+}
+
+// Returns the directory of the dataset.
+func (s SnapshotStore) datasetDir(name string) string {
+	return filepath.Join(s.dir, name)
+}
+
+// Returns the path of the snapshot of the dataset.
+func (s SnapshotStore) snapshotPath(name, id string) string {
+	return filepath.Join(s.datasetDir(name), snapshotFilePrefix+id+snapshotFileExt)
+}
+
+// Saves the fingerprints as a new snapshot of the dataset taken at the time
+// and returns the id of the snapshot, which is the time in UTC.
+func (s SnapshotStore) Save(name string, fingerprints [][]csvcheck.StringHashable, t time.Time) (string, error) {
+	err := checkSnapshotName(name)
+	if err != nil {
+		return "", err
+	}
+	id := t.UTC().Format(snapshotTimeLayout)
+	content, err := FormatCsvArray(fingerprints)
+	if err != nil {
+		return "", err
+	}
+	err = WriteFile(s.snapshotPath(name, id), content, WriteOptions{})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Returns the ids of the snapshots of the dataset, oldest first.
+func (s SnapshotStore) Snapshots(name string) ([]string, error) {
+	err := checkSnapshotName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(s.datasetDir(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(fileName, snapshotFilePrefix) && strings.HasSuffix(fileName, snapshotFileExt) {
+			ids = append(ids, strings.TrimSuffix(strings.TrimPrefix(fileName, snapshotFilePrefix), snapshotFileExt))
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Returns the fingerprints of the snapshot of the dataset.
+func (s SnapshotStore) Load(name, id string) ([][]csvcheck.StringHashable, error) {
+	err := checkSnapshotName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(s.snapshotPath(name, id))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadCsv(file)
+}
+
+// Returns the id and fingerprints of the latest snapshot of the dataset.
+func (s SnapshotStore) Latest(name string) (string, [][]csvcheck.StringHashable, error) {
+	ids, err := s.Snapshots(name)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(ids) == 0 {
+		return "", nil, fmt.Errorf("no snapshots of %s found in %s", name, s.dir)
+	}
+
+	id := ids[len(ids)-1]
+	fingerprints, err := s.Load(name, id)
+	if err != nil {
+		return "", nil, err
+	}
+	return id, fingerprints, nil
+}
+
+// Deletes all but the latest retain snapshots of the dataset and returns the ids
+// of the deleted ones. Values of retain of 0 or less keep every snapshot.
+func (s SnapshotStore) Prune(name string, retain int) ([]string, error) {
+	ids, err := s.Snapshots(name)
+	if err != nil {
+		return nil, err
+	}
+	if retain <= 0 || len(ids) <= retain {
+		return []string{}, nil
+	}
+
+	pruned := ids[:len(ids)-retain]
+	for _, id := range pruned {
+		err = os.Remove(s.snapshotPath(name, id))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pruned, nil
+}
+
+// Appends the entry to the history of its dataset.
+func (s SnapshotStore) AddHistory(entry HistoryEntry) error {
+	err := checkSnapshotName(entry.Name)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(s.datasetDir(entry.Name), 0755)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.datasetDir(entry.Name), historyFileName)
+	_, err = os.Stat(path)
+	writeHeader := errors.Is(err, fs.ErrNotExist)
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(file)
+	if writeHeader {
+		writer.Write(historyColumns)
+	}
+	writer.Write([]string{
+		entry.Time.Format(time.RFC3339Nano),
+		entry.Name,
+		entry.File,
+		strconv.Itoa(entry.Rows),
+		entry.Baseline,
+		strconv.Itoa(entry.New),
+		strconv.Itoa(entry.Removed),
+		strconv.Itoa(entry.Changed),
+		entry.Snapshot,
+	})
+	writer.Flush()
+	return errors.Join(writer.Error(), file.Close())
+}
+
+// Returns the recorded runs of the dataset, or of every dataset in the store if
+// name is empty, oldest first.
+func (s SnapshotStore) History(name string) ([]HistoryEntry, error) {
+	names := []string{name}
+	if name == "" {
+		entries, err := os.ReadDir(s.dir)
+		if err != nil {
+			return nil, err
+		}
+		names = []string{}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+	} else if err := checkSnapshotName(name); err != nil {
+		return nil, err
+	}
+
+	res := []HistoryEntry{}
+	for _, name := range names {
+		file, err := os.Open(filepath.Join(s.datasetDir(name), historyFileName))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		records, err := csv.NewReader(file).ReadAll()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for i, record := range records {
+			if i == 0 {
+				continue
+			}
+			entry, err := parseHistoryRecord(record)
+			if err != nil {
+				return nil, fmt.Errorf("line %d of the history of %s: %w", i+1, name, err)
+			}
+			res = append(res, entry)
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Time.Before(res[j].Time)
+	})
+	return res, nil
+}
+
+// Returns the history entry of a record of the history file.
+func parseHistoryRecord(record []string) (HistoryEntry, error) {
+	if len(record) != len(historyColumns) {
+		return HistoryEntry{}, fmt.Errorf("expected %d columns but got %d", len(historyColumns), len(record))
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, record[0])
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+	counts := make([]int, 4)
+	for i, s := range []string{record[3], record[5], record[6], record[7]} {
+		counts[i], err = strconv.Atoi(s)
+		if err != nil {
+			return HistoryEntry{}, err
+		}
+	}
+
+	return HistoryEntry{
+		Time:     t,
+		Name:     record[1],
+		File:     record[2],
+		Rows:     counts[0],
+		Baseline: record[4],
+		New:      counts[1],
+		Removed:  counts[2],
+		Changed:  counts[3],
+		Snapshot: record[8],
+	}, nil
+}
+
+// Returns the history entries as a csv array with the columns row. The counts of
+// new, removed and changed rows are left empty for runs without a baseline.
+func GetHistoryArray(entries []HistoryEntry) [][]csvcheck.StringHashable {
+	res := [][]csvcheck.StringHashable{csvcheck.GetRowFromRow(historyColumns)}
+	for _, entry := range entries {
+		newCount, removedCount, changedCount := "", "", ""
+		if entry.Baseline != "" {
+			newCount = strconv.Itoa(entry.New)
+			removedCount = strconv.Itoa(entry.Removed)
+			changedCount = strconv.Itoa(entry.Changed)
+		}
+		res = append(res, csvcheck.GetRowFromRow([]string{
+			entry.Time.Format("2006-01-02 15:04:05"),
+			entry.Name,
+			entry.File,
+			strconv.Itoa(entry.Rows),
+			entry.Baseline,
+			newCount,
+			removedCount,
+			changedCount,
+			entry.Snapshot,
+		}))
+	}
+	return res
+}
+
+// Checks if the snapshot options are valid.
+func (c SnapshotConfig) Validate() error {
+	err := c.FingerprintConfig().Validate()
+	if err != nil {
+		return err
+	}
+
+	if c.SnapshotDir == "" {
+		return fmt.Errorf("snapshotdir must be given")
+	}
+	if c.Name == "" && c.AgainstBaseline == "" {
+		return fmt.Errorf("name or against-baseline must be given")
+	}
+	if c.NoSave && c.AgainstBaseline == "" {
+		return fmt.Errorf("nosave requires against-baseline")
+	}
+	for _, name := range []string{c.Name, c.AgainstBaseline} {
+		if name == "" {
+			continue
+		}
+		err = checkSnapshotName(name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the options the fingerprints of the snapshot are taken with.
+func (c SnapshotConfig) FingerprintConfig() FingerprintConfig {
+	return FingerprintConfig{
+		InputDir:         c.InputDir,
+		File:             c.File,
+		KeyColumns:       c.KeyColumns,
+		ColumnsToUse:     c.ColumnsToUse,
+		ColumnsToIgnore:  c.ColumnsToIgnore,
+		NormalizeHeaders: c.NormalizeHeaders,
+		NoHeader:         c.NoHeader,
+	}
+}
+
+// Returns the name of the dataset the snapshot is saved under and the run is recorded for.
+func (c SnapshotConfig) DatasetName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.AgainstBaseline
+}
+
+// Parses the arguments of the snapshot command (without the program and command
+// names) into a SnapshotConfig. If help is requested, pflag.ErrHelp is returned.
+func ParseSnapshotArgs(args []string) (SnapshotConfig, error) {
+	var cfg SnapshotConfig
+	flags := pflag.NewFlagSet("csvcheckcli snapshot", pflag.ContinueOnError)
+	flags.StringVarP(&cfg.InputDir, "inputdir", "d", "", "The directory containing the input file. This will be prepended to the input file path. Must be given.")
+	flags.StringVarP(&cfg.File, "file", "f", "", "The input file path to take a snapshot of. Must be given.")
+	flags.StringSliceVar(&cfg.KeyColumns, "keycolumns", nil, "The columns identifying a row. Must be given and be the same for every snapshot of a dataset.")
+	flags.StringSliceVarP(&cfg.ColumnsToUse, "usecolumns", "c", nil, "The columns to fingerprint.")
+	flags.StringSliceVarP(&cfg.ColumnsToIgnore, "ignorecolumns", "i", nil, "The columns to leave out of the fingerprint.")
+	flags.BoolVarP(&cfg.NormalizeHeaders, "normalizeheaders", "n", false, "Whether to match headers and given column names case-insensitively, ignoring surrounding whitespace, byte order marks and the kind of separators used (spaces, underscores, hyphens, dots).")
+	flags.BoolVar(&cfg.NoHeader, "noheader", false, fmt.Sprintf("Whether the csv file has no header row. Columns will be named %s1, %s2, ... and can also be referenced by position (#1, #2, ...).", GeneratedColumnPrefix, GeneratedColumnPrefix))
+	flags.StringVar(&cfg.SnapshotDir, "snapshotdir", "", "The directory of the snapshot store. Must be given.")
+	flags.StringVar(&cfg.Name, "name", "", "The name of the dataset to save the snapshot under. By default, the name given in against-baseline is used.")
+	flags.StringVar(&cfg.AgainstBaseline, "against-baseline", "", "The name of a dataset whose latest snapshot the new, removed and changed rows are reported against.")
+	flags.IntVar(&cfg.Retain, "retain", 0, "The number of latest snapshots of the dataset to keep after saving. Values of 0 or less keep every snapshot.")
+	flags.BoolVar(&cfg.NoSave, "nosave", false, "Whether to only report against the baseline without saving a new snapshot.")
+	err := flags.Parse(args)
+	if err != nil {
+		return SnapshotConfig{}, err
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return SnapshotConfig{}, err
+	}
+	return cfg, nil
+}
+
+// Parses the arguments of the history command (without the program and command
+// names) into a HistoryConfig. If help is requested, pflag.ErrHelp is returned.
+func ParseHistoryArgs(args []string) (HistoryConfig, error) {
+	var cfg HistoryConfig
+	flags := pflag.NewFlagSet("csvcheckcli history", pflag.ContinueOnError)
+	flags.StringVar(&cfg.SnapshotDir, "snapshotdir", "", "The directory of the snapshot store. Must be given.")
+	flags.StringVar(&cfg.Name, "name", "", "The name of the dataset to list the runs of. By default, the runs of every dataset are listed.")
+	flags.BoolVarP(&cfg.PrintInCsvFormat, "csv", "p", false, "Whether to print the output in csv format. By default, the output is printed in a columns-aligned.")
+	err := flags.Parse(args)
+	if err != nil {
+		return HistoryConfig{}, err
+	}
+
+	if cfg.SnapshotDir == "" {
+		return HistoryConfig{}, fmt.Errorf("snapshotdir must be given")
+	}
+	return cfg, nil
+}
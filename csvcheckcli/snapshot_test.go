@@ -0,0 +1,151 @@
+package csvcheckcli_test
+
+import (
+	"csvcheckcli/csvcheckcli"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotStoreSaveLatestPrune(t *testing.T) {
+	store := csvcheckcli.NewSnapshotStore(t.TempDir())
+	cfg := csvcheckcli.FingerprintConfig{KeyColumns: []string{"id"}}
+	start := time.Date(2024, 9, 25, 13, 51, 22, 0, time.UTC)
+
+	_, _, err := store.Latest("orders")
+	assert.NotNil(t, err)
+
+	ids := []string{}
+	for i := 0; i < 3; i++ {
+		fingerprints, _ := csvcheckcli.GetFingerprintArray(Get2DArrayFromCsvString(fmt.Sprintf("id,a\n1,%d\n", i)), cfg)
+		id, err := store.Save("orders", fingerprints, start.Add(time.Duration(i)*time.Hour))
+		assert.Nil(t, err)
+		ids = append(ids, id)
+	}
+
+	id, fingerprints, err := store.Latest("orders")
+	expected, _ := csvcheckcli.GetFingerprintArray(Get2DArrayFromCsvString("id,a\n1,2\n"), cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, ids[2], id)
+	assert.Equal(t, expected, fingerprints)
+
+	pruned, err := store.Prune("orders", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, ids[:1], pruned)
+
+	remaining, err := store.Snapshots("orders")
+	assert.Nil(t, err)
+	assert.Equal(t, ids[1:], remaining)
+}
+
+func TestSnapshotStoreSaveAcrossDaylightSavingTimeEnd(t *testing.T) {
+	store := csvcheckcli.NewSnapshotStore(t.TempDir())
+	cfg := csvcheckcli.FingerprintConfig{KeyColumns: []string{"id"}}
+	// The local time is turned back from 2:00 EDT to 1:00 EST, so the newer snapshot
+	// is taken at an earlier local time.
+	times := []time.Time{
+		time.Date(2024, 11, 3, 1, 30, 0, 0, time.FixedZone("EDT", -4*60*60)),
+		time.Date(2024, 11, 3, 1, 10, 0, 0, time.FixedZone("EST", -5*60*60)),
+	}
+
+	ids := []string{}
+	for i, snapshotTime := range times {
+		fingerprints, _ := csvcheckcli.GetFingerprintArray(Get2DArrayFromCsvString(fmt.Sprintf("id,a\n1,%d\n", i)), cfg)
+		id, err := store.Save("orders", fingerprints, snapshotTime)
+		assert.Nil(t, err)
+		ids = append(ids, id)
+	}
+
+	id, fingerprints, err := store.Latest("orders")
+	expected, _ := csvcheckcli.GetFingerprintArray(Get2DArrayFromCsvString("id,a\n1,1\n"), cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, ids[1], id)
+	assert.Equal(t, expected, fingerprints)
+
+	pruned, err := store.Prune("orders", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, ids[:1], pruned)
+}
+
+func TestSnapshotStoreSaveQuotedValues(t *testing.T) {
+	store := csvcheckcli.NewSnapshotStore(t.TempDir())
+	fingerprints, err := csvcheckcli.GetFingerprintArray([][]csvcheck.StringHashable{
+		csvcheck.GetRowFromRow([]string{"name", "amount"}),
+		csvcheck.GetRowFromRow([]string{"Smith, John", "10"}),
+		csvcheck.GetRowFromRow([]string{"say \"hi\"\nagain", "20"}),
+	}, csvcheckcli.FingerprintConfig{KeyColumns: []string{"name"}})
+	assert.Nil(t, err)
+
+	_, err = store.Save("people", fingerprints, time.Date(2024, 9, 25, 13, 51, 22, 0, time.UTC))
+	assert.Nil(t, err)
+	_, latest, err := store.Latest("people")
+
+	assert.Nil(t, err)
+	assert.Equal(t, fingerprints, latest)
+}
+
+func TestSnapshotStoreHistory(t *testing.T) {
+	store := csvcheckcli.NewSnapshotStore(t.TempDir())
+	start := time.Date(2024, 9, 25, 13, 51, 22, 0, time.UTC)
+
+	entries := []csvcheckcli.HistoryEntry{
+		{Time: start, Name: "orders", File: "a.csv", Rows: 3, Snapshot: "s1"},
+		{Time: start.Add(time.Minute), Name: "customers", File: "c.csv", Rows: 5, Snapshot: "s2"},
+		{Time: start.Add(time.Hour), Name: "orders", File: "b.csv", Rows: 4, Baseline: "s1", New: 1, Removed: 0, Changed: 2},
+	}
+	for _, entry := range entries {
+		assert.Nil(t, store.AddHistory(entry))
+	}
+
+	history, err := store.History("orders")
+	assert.Nil(t, err)
+	assert.Equal(t, []csvcheckcli.HistoryEntry{entries[0], entries[2]}, history)
+
+	history, err = store.History("")
+	assert.Nil(t, err)
+	assert.Equal(t, entries, history)
+
+	historyArray := csvcheckcli.GetHistoryArray(history[:1])
+	assert.Equal(t, Get2DArrayFromCsvString(`
+time,name,file,rows,baseline,new,removed,changed,snapshot
+2024-09-25 13:51:22,orders,a.csv,3,,,,,s1
+`), historyArray)
+}
+
+func TestParseSnapshotArgsProperAndImproperInputs(t *testing.T) {
+	for i, data := range []struct {
+		args        []string
+		expectError bool
+	}{
+		{args: []string{"-d", "dir", "-f", "a.csv", "--keycolumns", "id", "--snapshotdir", "snaps", "--name", "orders"}, expectError: false},
+		{args: []string{"-d", "dir", "-f", "a.csv", "--keycolumns", "id", "--snapshotdir", "snaps", "--against-baseline", "orders", "--nosave"}, expectError: false},
+		{args: []string{"-d", "dir", "-f", "a.csv", "--keycolumns", "id", "--snapshotdir", "snaps"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "a.csv", "--keycolumns", "id", "--name", "orders"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "a.csv", "--snapshotdir", "snaps", "--name", "orders"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "a.csv", "--keycolumns", "id", "--snapshotdir", "snaps", "--name", "../orders"}, expectError: true},
+		{args: []string{"-d", "dir", "-f", "a.csv", "--keycolumns", "id", "--snapshotdir", "snaps", "--name", "orders", "--nosave"}, expectError: true},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		_, err := csvcheckcli.ParseSnapshotArgs(data.args)
+		if data.expectError {
+			assert.NotNil(t, err, indexString)
+		} else {
+			assert.Nil(t, err, indexString)
+		}
+	}
+}
+
+func TestSnapshotConfigFingerprintConfig(t *testing.T) {
+	cfg, err := csvcheckcli.ParseSnapshotArgs([]string{"-d", "dir", "-f", "a.csv", "--keycolumns", "id", "-i", "note", "-n", "--snapshotdir", "snaps", "--name", "orders"})
+	assert.Nil(t, err)
+	assert.Equal(t, csvcheckcli.FingerprintConfig{
+		InputDir:         "dir",
+		File:             "a.csv",
+		KeyColumns:       []string{"id"},
+		ColumnsToIgnore:  []string{"note"},
+		NormalizeHeaders: true,
+	}, cfg.FingerprintConfig())
+}
@@ -0,0 +1,331 @@
+package csvcheckcli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+)
+
+// The directions result rows can be sorted in.
+const (
+	SortAscending  = "asc"
+	SortDescending = "desc"
+)
+
+// The types values can be compared as when sorting. Without one, the type of a
+// column is detected from its values.
+const (
+	SortTypeNumeric = "numeric"
+	SortTypeDate    = "date"
+	SortTypeString  = "string"
+	SortTypeNatural = "natural"
+)
+
+// The types a sort key can be given.
+var sortTypes = map[string]bool{
+	SortTypeNumeric: true,
+	SortTypeDate:    true,
+	SortTypeString:  true,
+	SortTypeNatural: true,
+}
+
+// The layouts dates are parsed with when sorting, in the order they are tried.
+var sortDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"2006/01/02",
+	"01/02/2006",
+	"02.01.2006",
+}
+
+// For holding a column result rows are sorted by.
+type SortKey struct {
+	Column     string
+	Descending bool
+	Type       string // Empty if the type is detected from the values.
+}
+
+// Parses sort keys of the form column[:asc|desc][:numeric|date|string|natural].
+// The direction and type may be given in either order. Column names may contain
+// colons, as only the known directions and types are taken off their end.
+func ParseSortKeys(specs []string) ([]SortKey, error) {
+	res := make([]SortKey, 0, len(specs))
+	for _, spec := range specs {
+		key := SortKey{}
+		parts := strings.Split(spec, ":")
+		directionGiven := false
+		for len(parts) > 1 {
+			modifier := strings.ToLower(parts[len(parts)-1])
+			if !directionGiven && (modifier == SortAscending || modifier == SortDescending) {
+				key.Descending = modifier == SortDescending
+				directionGiven = true
+			} else if key.Type == "" && sortTypes[modifier] {
+				key.Type = modifier
+			} else {
+				break
+			}
+			parts = parts[:len(parts)-1]
+		}
+		key.Column = strings.Join(parts, ":")
+		if key.Column == "" {
+			return nil, fmt.Errorf("sortby %q has no column", spec)
+		}
+		res = append(res, key)
+	}
+	return res, nil
+}
+
+// Returns the sort keys with their columns resolved against the headers like other
+// given columns.
+func resolveSortKeys(specs []string, normalize bool, headers ...[]csvcheck.StringHashable) ([]SortKey, error) {
+	keys, err := ParseSortKeys(specs)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []SortKey{}
+	for _, key := range keys {
+		columns, err := resolveColumns("sortby", []string{key.Column}, normalize, headers...)
+		if err != nil {
+			return nil, err
+		}
+		key.Column = columns[0].StringHash()
+		res = append(res, key)
+	}
+	return res, nil
+}
+
+// For holding a value of a sort key column parsed for comparison.
+type sortValue struct {
+	text   string
+	empty  bool
+	parsed bool // Whether the value is a number or date, depending on the type.
+	number float64
+	date   time.Time
+}
+
+// Returns the layout all non-empty values parse with as dates, or an empty string if there is none.
+func detectDateLayout(values []string) string {
+	for _, layout := range sortDateLayouts {
+		matches := false
+		for _, value := range values {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			if _, err := time.Parse(layout, value); err != nil {
+				matches = false
+				break
+			}
+			matches = true
+		}
+		if matches {
+			return layout
+		}
+	}
+	return ""
+}
+
+// Returns the type of the values: numeric if all non-empty values are finite numbers, date
+// if they are all dates of the same layout and string otherwise.
+func detectSortType(values []string) string {
+	numeric := false
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if _, isNumber := parseNumber(value); !isNumber {
+			numeric = false
+			break
+		}
+		numeric = true
+	}
+	if numeric {
+		return SortTypeNumeric
+	}
+	if detectDateLayout(values) != "" {
+		return SortTypeDate
+	}
+	return SortTypeString
+}
+
+// Returns the values parsed as the type. Numbers that are not finite, such as NaN, are
+// left unparsed. Dates are parsed with the first layout that fits.
+func parseSortValues(values []string, sortType string) []sortValue {
+	res := make([]sortValue, len(values))
+	for i, value := range values {
+		trimmed := strings.TrimSpace(value)
+		v := sortValue{text: value, empty: trimmed == ""}
+		switch sortType {
+		case SortTypeNumeric:
+			v.number, v.parsed = parseNumber(trimmed)
+		case SortTypeDate:
+			for _, layout := range sortDateLayouts {
+				date, err := time.Parse(layout, trimmed)
+				if err == nil {
+					v.date, v.parsed = date, true
+					break
+				}
+			}
+		}
+		res[i] = v
+	}
+	return res
+}
+
+// Compares two strings treating runs of digits as numbers, so that item2 comes before item10.
+func compareNatural(s1, s2 string) int {
+	i, j := 0, 0
+	for i < len(s1) && j < len(s2) {
+		if isDigit(s1[i]) && isDigit(s2[j]) {
+			start1, start2 := i, j
+			for i < len(s1) && isDigit(s1[i]) {
+				i++
+			}
+			for j < len(s2) && isDigit(s2[j]) {
+				j++
+			}
+			digits1 := strings.TrimLeft(s1[start1:i], "0")
+			digits2 := strings.TrimLeft(s2[start2:j], "0")
+			if len(digits1) != len(digits2) {
+				return compareInts(len(digits1), len(digits2))
+			}
+			if c := strings.Compare(digits1, digits2); c != 0 {
+				return c
+			}
+			continue
+		}
+		if s1[i] != s2[j] {
+			return compareInts(int(s1[i]), int(s2[j]))
+		}
+		i++
+		j++
+	}
+	return compareInts(len(s1)-i, len(s2)-j)
+}
+
+// Returns true iff the byte is an ASCII digit.
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// Returns -1, 0 or 1 if a is less than, equal to or greater than b.
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Compares two values of the type. Numbers and dates that do not parse come after
+// the ones that do and are compared as strings among themselves.
+func compareSortValues(v1, v2 sortValue, sortType string) int {
+	if sortType == SortTypeNumeric || sortType == SortTypeDate {
+		if v1.parsed != v2.parsed {
+			if v1.parsed {
+				return -1
+			}
+			return 1
+		}
+		if v1.parsed {
+			if sortType == SortTypeNumeric {
+				switch {
+				case v1.number < v2.number:
+					return -1
+				case v1.number > v2.number:
+					return 1
+				}
+				return 0
+			}
+			return v1.date.Compare(v2.date)
+		}
+	}
+	if sortType == SortTypeNatural {
+		return compareNatural(v1.text, v2.text)
+	}
+	return strings.Compare(v1.text, v2.text)
+}
+
+// Returns the result array with its rows stably sorted by the keys, leaving arr as it
+// is. Keys of columns the array does not have are skipped. Empty values come last in
+// either direction.
+func SortResArray(arr [][]csvcheck.StringHashable, keys []SortKey) [][]csvcheck.StringHashable {
+	if len(arr) <= 2 || len(keys) == 0 {
+		return arr
+	}
+	rows := arr[1:]
+
+	type column struct {
+		values     []sortValue
+		descending bool
+		sortType   string
+	}
+	columns := []column{}
+	for _, key := range keys {
+		index := getColumnIndex(arr[0], key.Column)
+		if index < 0 {
+			continue
+		}
+		values := make([]string, len(rows))
+		for i, row := range rows {
+			if index < len(row) {
+				values[i] = row[index].StringHash()
+			}
+		}
+		sortType := key.Type
+		if sortType == "" {
+			sortType = detectSortType(values)
+		}
+		columns = append(columns, column{parseSortValues(values, sortType), key.Descending, sortType})
+	}
+
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		for _, c := range columns {
+			v1, v2 := c.values[order[a]], c.values[order[b]]
+			if v1.empty != v2.empty {
+				return v2.empty
+			}
+			if v1.empty {
+				continue
+			}
+			comparison := compareSortValues(v1, v2, c.sortType)
+			if c.descending {
+				comparison = -comparison
+			}
+			if comparison != 0 {
+				return comparison < 0
+			}
+		}
+		return false
+	})
+
+	res := make([][]csvcheck.StringHashable, len(arr))
+	res[0] = arr[0]
+	for i, j := range order {
+		res[i+1] = rows[j]
+	}
+	return res
+}
+
+// Returns the index of the first column with the name in the header, or -1 if there is none.
+func getColumnIndex(header []csvcheck.StringHashable, name string) int {
+	for i, column := range header {
+		if column.StringHash() == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,154 @@
+package csvcheckcli_test
+
+import (
+	"context"
+	"csvcheckcli/csvcheckcli"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSortKeys(t *testing.T) {
+	for i, data := range []struct {
+		specs       []string
+		expected    []csvcheckcli.SortKey
+		expectError bool
+	}{
+		{
+			specs:    []string{"amount:desc", "name"},
+			expected: []csvcheckcli.SortKey{{Column: "amount", Descending: true}, {Column: "name"}},
+		},
+		{
+			specs:    []string{"id:asc:natural", "date:DATE:desc"},
+			expected: []csvcheckcli.SortKey{{Column: "id", Type: csvcheckcli.SortTypeNatural}, {Column: "date", Descending: true, Type: csvcheckcli.SortTypeDate}},
+		},
+		{
+			specs:    []string{"a:b:numeric", "time:asc:desc"},
+			expected: []csvcheckcli.SortKey{{Column: "a:b", Type: csvcheckcli.SortTypeNumeric}, {Column: "time:asc", Descending: true}},
+		},
+		{
+			specs:       []string{":desc"},
+			expectError: true,
+		},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		keys, err := csvcheckcli.ParseSortKeys(data.specs)
+		if data.expectError {
+			assert.NotNil(t, err, indexString)
+			continue
+		}
+		assert.Nil(t, err, indexString)
+		assert.Equal(t, data.expected, keys, indexString)
+	}
+}
+
+func TestSortResArray(t *testing.T) {
+	for i, data := range []struct {
+		arr      string
+		keys     []csvcheckcli.SortKey
+		expected string
+	}{
+		{
+			arr:      "name,amount\na,9\nb,10\nc,-1.5\nd,\n",
+			keys:     []csvcheckcli.SortKey{{Column: "amount"}},
+			expected: "name,amount\nc,-1.5\na,9\nb,10\nd,\n",
+		},
+		{
+			arr:      "name,amount\na,9\nb,10\nc,-1.5\nd,\n",
+			keys:     []csvcheckcli.SortKey{{Column: "amount", Descending: true}},
+			expected: "name,amount\nb,10\na,9\nc,-1.5\nd,\n",
+		},
+		{
+			arr:      "name,amount\na,NaN\nb,10\nc,-Inf\nd,9\n",
+			keys:     []csvcheckcli.SortKey{{Column: "amount", Type: csvcheckcli.SortTypeNumeric}},
+			expected: "name,amount\nd,9\nb,10\nc,-Inf\na,NaN\n",
+		},
+		{
+			arr:      "name,amount\na,NaN\nb,10\nc,9\n",
+			keys:     []csvcheckcli.SortKey{{Column: "amount"}},
+			expected: "name,amount\nb,10\nc,9\na,NaN\n",
+		},
+		{
+			arr:      "name,amount\na,9\nb,10\n",
+			keys:     []csvcheckcli.SortKey{{Column: "amount", Type: csvcheckcli.SortTypeString}},
+			expected: "name,amount\nb,10\na,9\n",
+		},
+		{
+			arr:      "file\nitem10\nitem2\nitem02b\nItem1\n",
+			keys:     []csvcheckcli.SortKey{{Column: "file", Type: csvcheckcli.SortTypeNatural}},
+			expected: "file\nItem1\nitem2\nitem02b\nitem10\n",
+		},
+		{
+			arr:      "day\n2024-03-01\n2023-12-31\n2024-01-15\n",
+			keys:     []csvcheckcli.SortKey{{Column: "day"}},
+			expected: "day\n2023-12-31\n2024-01-15\n2024-03-01\n",
+		},
+		{
+			arr:      "day\n03/01/2024\n12/31/2023\nunknown\n",
+			keys:     []csvcheckcli.SortKey{{Column: "day", Type: csvcheckcli.SortTypeDate}},
+			expected: "day\n12/31/2023\n03/01/2024\nunknown\n",
+		},
+		{
+			arr:      "group,amount,id\nb,1,1\na,2,2\nb,2,3\na,2,4\nb,1,5\n",
+			keys:     []csvcheckcli.SortKey{{Column: "amount", Descending: true}, {Column: "group"}},
+			expected: "group,amount,id\na,2,2\na,2,4\nb,2,3\nb,1,1\nb,1,5\n",
+		},
+		{
+			arr:      "name\nb\na\n",
+			keys:     []csvcheckcli.SortKey{{Column: "missing"}},
+			expected: "name\nb\na\n",
+		},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		arr := Get2DArrayFromCsvString(data.arr)
+		res := csvcheckcli.SortResArray(arr, data.keys)
+		assert.Equal(t, Get2DArrayFromCsvString(data.expected), res, indexString)
+		assert.Equal(t, Get2DArrayFromCsvString(data.arr), arr, indexString)
+	}
+}
+
+func TestCompareSortBy(t *testing.T) {
+	csvString1 := "name,amount\nx,5\ny,20\nz,3\nw,1\n"
+	csvString2 := "name,amount\nw,1\nv,100\nu,7\n"
+
+	for i, data := range []struct {
+		sortBy      []string
+		expected1   string
+		expected2   string
+		expectError bool
+	}{
+		{
+			sortBy:    []string{"amount:desc"},
+			expected1: "name,amount,_ind\ny,20,2\nx,5,1\nz,3,3\n",
+			expected2: "name,amount,_ind\nv,100,2\nu,7,3\n",
+		},
+		{
+			sortBy:    []string{"_ind:desc"},
+			expected1: "name,amount,_ind\nz,3,3\ny,20,2\nx,5,1\n",
+			expected2: "name,amount,_ind\nu,7,3\nv,100,2\n",
+		},
+		{
+			sortBy:      []string{"AMOUNT"},
+			expectError: true,
+		},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		cfg := csvcheckcli.NewConfig(
+			csvcheckcli.WithFunction(csvcheckcli.FunctionStringDifferent),
+			csvcheckcli.WithKeepIndex(true),
+		)
+		cfg.SortBy = data.sortBy
+
+		result, err := csvcheckcli.Compare(context.Background(), strings.NewReader(csvString1), strings.NewReader(csvString2), cfg)
+
+		if data.expectError {
+			assert.NotNil(t, err, indexString)
+			continue
+		}
+		assert.Nil(t, err, indexString)
+		assert.Equal(t, Get2DArrayFromCsvString(data.expected1), result.Rows1, indexString)
+		assert.Equal(t, Get2DArrayFromCsvString(data.expected2), result.Rows2, indexString)
+	}
+}
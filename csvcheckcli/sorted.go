@@ -0,0 +1,285 @@
+package csvcheckcli
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+)
+
+// Reads the next record of the csv file, returning nil at the end of the file.
+func readRecord(reader *csv.Reader) ([]csvcheck.StringHashable, error) {
+	record, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return csvcheck.GetRowFromRow(record), nil
+}
+
+// Returns the positions of the compared columns in both headers, in the order
+// that the rows are expected to be sorted by.
+func getSortedComparisonIndices(header1, header2 []csvcheck.StringHashable, columns resolvedColumns) ([]int, []int, error) {
+	err := csvcheck.CheckForProperCsvArray([][]csvcheck.StringHashable{header1})
+	if err != nil {
+		return nil, nil, err
+	}
+	err = csvcheck.CheckForProperCsvArray([][]csvcheck.StringHashable{header2})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	positions1 := make(map[string]int)
+	for i, column := range header1 {
+		positions1[column.StringHash()] = i
+	}
+	positions2 := make(map[string]int)
+	for i, column := range header2 {
+		positions2[column.StringHash()] = i
+	}
+
+	var compared []csvcheck.StringHashable
+	if columns.toUse != nil {
+		compared = columns.toUse
+	} else {
+		ignored := make(map[string]bool)
+		for _, column := range columns.toIgnore {
+			ignored[column.StringHash()] = true
+		}
+		compared = []csvcheck.StringHashable{}
+		for _, column := range header1 {
+			if !ignored[column.StringHash()] {
+				compared = append(compared, column)
+			}
+		}
+
+		comparedCount2 := 0
+		for _, column := range header2 {
+			if !ignored[column.StringHash()] {
+				comparedCount2++
+			}
+		}
+		if comparedCount2 != len(compared) {
+			return nil, nil, fmt.Errorf("check the columns being compared")
+		}
+	}
+
+	if len(compared) == 0 {
+		return nil, nil, fmt.Errorf("no columns to compare")
+	}
+
+	indices1 := make([]int, len(compared))
+	indices2 := make([]int, len(compared))
+	for i, column := range compared {
+		position1, exists1 := positions1[column.StringHash()]
+		position2, exists2 := positions2[column.StringHash()]
+		if !exists1 || !exists2 {
+			return nil, nil, fmt.Errorf("check the columns being compared")
+		}
+		indices1[i] = position1
+		indices2[i] = position2
+	}
+	return indices1, indices2, nil
+}
+
+// Returns a negative number, zero or a positive number if the values of the first row
+// at indices1 are respectively smaller than, equal to or greater than the values of the
+// second row at indices2, comparing the values byte-wise from left to right.
+func compareSortKeys(row1 []csvcheck.StringHashable, indices1 []int, row2 []csvcheck.StringHashable, indices2 []int) int {
+	for i := range indices1 {
+		cmp := strings.Compare(row1[indices1[i]].StringHash(), row2[indices2[i]].StringHash())
+		if cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+// A csv file being merged that checks that its rows are sorted by the compared columns.
+type sortedCsvStream struct {
+	name          string
+	reader        *csv.Reader
+	pending       []csvcheck.StringHashable
+	pendingLine   int
+	columnIndices []int
+	current       []csvcheck.StringHashable
+	index         int
+}
+
+// Moves on to the next row of the file. The current row is nil at the end of the file.
+func (s *sortedCsvStream) next() error {
+	previous := s.current
+
+	var row []csvcheck.StringHashable
+	var line int
+	if s.pending != nil {
+		row, line = s.pending, s.pendingLine
+		s.pending = nil
+	} else {
+		var err error
+		row, err = readRecord(s.reader)
+		if err != nil {
+			return err
+		}
+		if row != nil {
+			line, _ = s.reader.FieldPos(0)
+		}
+	}
+
+	if row != nil && previous != nil && compareSortKeys(previous, s.columnIndices, row, s.columnIndices) > 0 {
+		return fmt.Errorf("%s file is not sorted by the compared columns at line %d", s.name, line)
+	}
+
+	s.current = row
+	s.index++
+	return nil
+}
+
+// Reads the header row of a csv file (generating it if the file has none) and returns
+// the stream of the remaining rows.
+func openSortedCsvStream(name string, reader *csv.Reader, noHeader bool) ([]csvcheck.StringHashable, *sortedCsvStream, error) {
+	first, err := readRecord(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if first == nil {
+		return nil, nil, fmt.Errorf("empty array")
+	}
+
+	stream := &sortedCsvStream{name: name, reader: reader}
+	if !noHeader {
+		return first, stream, nil
+	}
+
+	stream.pending = first
+	stream.pendingLine, _ = reader.FieldPos(0)
+	return addGeneratedHeaderRow([][]csvcheck.StringHashable{first})[0], stream, nil
+}
+
+// Gets the result arrays based off of user input by merging two csv files that are
+// sorted by the compared columns. The files are streamed and only the result rows
+// are kept in memory. Rows are paired up like in the match method and an error is
+// returned as soon as a row is found out of order.
+func GetResArraysSorted(reader1, reader2 *csv.Reader, input UserInput) ([][]csvcheck.StringHashable, [][]csvcheck.StringHashable, error) {
+	return getResArraysSorted(context.Background(), reader1, reader2, input.Config(), nil)
+}
+
+// Gets the result arrays based off of the config by merging two sorted csv files.
+// If the context is cancelled, the merge stops and the results found so far are
+// returned together with the context's error.
+func getResArraysSorted(ctx context.Context, reader1, reader2 *csv.Reader, cfg Config, tracker *progressTracker) ([][]csvcheck.StringHashable, [][]csvcheck.StringHashable, error) {
+	var common bool
+	switch cfg.Function {
+	case FunctionStringCommon:
+		common = true
+	case FunctionStringDifferent:
+		common = false
+	default:
+		return nil, nil, fmt.Errorf("unsupported function")
+	}
+
+	header1, stream1, err := openSortedCsvStream("first", reader1, cfg.NoHeader1)
+	if err != nil {
+		return nil, nil, err
+	}
+	header2, stream2, err := openSortedCsvStream("second", reader2, cfg.NoHeader2)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	headerArray1, headerArray2, err := prepareCsvArrays([][]csvcheck.StringHashable{header1}, [][]csvcheck.StringHashable{header2}, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	header1 = headerArray1[0]
+	header2 = headerArray2[0]
+
+	columns, err := resolveInputColumns(header1, header2, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stream1.columnIndices, stream2.columnIndices, err = getSortedComparisonIndices(header1, header2, columns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res1 := [][]csvcheck.StringHashable{header1}
+	res2 := [][]csvcheck.StringHashable{header2}
+	indices1 := []int{0}
+	indices2 := []int{0}
+
+	err = stream1.next()
+	if err != nil {
+		return nil, nil, err
+	}
+	err = stream2.next()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var interruption error
+	for merged := 1; stream1.current != nil || stream2.current != nil; merged++ {
+		if merged%progressInterval == 0 {
+			tracker.reportReading(1, int64(stream1.index), reader1.InputOffset())
+			tracker.reportReading(2, int64(stream2.index), reader2.InputOffset())
+			if interruption = ctx.Err(); interruption != nil {
+				break
+			}
+		}
+
+		cmp := 0
+		if stream1.current == nil {
+			cmp = 1
+		} else if stream2.current == nil {
+			cmp = -1
+		} else {
+			cmp = compareSortKeys(stream1.current, stream1.columnIndices, stream2.current, stream2.columnIndices)
+		}
+
+		if cmp <= 0 && (cmp == 0) == common {
+			res1 = append(res1, stream1.current)
+			indices1 = append(indices1, stream1.index)
+		}
+		if cmp >= 0 && (cmp == 0) == common {
+			res2 = append(res2, stream2.current)
+			indices2 = append(indices2, stream2.index)
+		}
+
+		if cmp <= 0 {
+			err = stream1.next()
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		if cmp >= 0 {
+			err = stream2.next()
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if interruption == nil {
+		tracker.reportReading(1, int64(stream1.index-1), reader1.InputOffset())
+		tracker.reportReading(2, int64(stream2.index-1), reader2.InputOffset())
+	}
+
+	if cfg.AutoAlign {
+		res1, res2, err = csvcheck.AutoAlignCsvArrays(res1, res2)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	res1, res2, err = finishResArrays(res1, res2, indices1, indices2, columns, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res1, res2, interruption
+}
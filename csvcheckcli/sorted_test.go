@@ -0,0 +1,108 @@
+package csvcheckcli_test
+
+import (
+	"csvcheckcli/csvcheckcli"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetResArraysSortedMatchesMatchMethod(t *testing.T) {
+	csvString1 := `
+id,name,amount
+1,a,10
+2,b,20
+2,b,20
+2,b,21
+4,d,40
+5,e,50
+`
+	csvString2 := `
+amount,id,name
+10,1,a
+20,2,b
+21,2,b
+30,3,c
+41,4,d
+`
+
+	for i, data := range []struct {
+		function     string
+		columnsToUse []string
+		autoAlign    bool
+	}{
+		{function: csvcheckcli.FunctionStringCommon},
+		{function: csvcheckcli.FunctionStringDifferent},
+		{function: csvcheckcli.FunctionStringCommon, columnsToUse: []string{"id", "name"}},
+		{function: csvcheckcli.FunctionStringDifferent, columnsToUse: []string{"id"}, autoAlign: true},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		solid := userInputSolid{
+			inputDir:     "/path/to/input/dir",
+			files:        []string{"file1.csv", "file2.csv"},
+			method:       csvcheckcli.MethodStringMatch,
+			function:     data.function,
+			keepIndex:    true,
+			columnsToUse: data.columnsToUse,
+			autoAlign:    data.autoAlign,
+		}
+		expected1, expected2, err := csvcheckcli.GetResArrays(Get2DArrayFromCsvString(csvString1), Get2DArrayFromCsvString(csvString2), solid.getUserInput())
+		assert.Nil(t, err, indexString)
+
+		solid.method = csvcheckcli.MethodStringSorted
+		res1, res2, err := csvcheckcli.GetResArraysSorted(csv.NewReader(strings.NewReader(csvString1)), csv.NewReader(strings.NewReader(csvString2)), solid.getUserInput())
+		assert.Nil(t, err, indexString)
+		assert.Equal(t, expected1, res1, indexString)
+		assert.Equal(t, expected2, res2, indexString)
+	}
+}
+
+func TestGetResArraysSortedNoHeader(t *testing.T) {
+	input := userInputSolid{
+		inputDir:  "/path/to/input/dir",
+		files:     []string{"file1.csv", "file2.csv"},
+		method:    csvcheckcli.MethodStringSorted,
+		function:  csvcheckcli.FunctionStringDifferent,
+		keepIndex: true,
+		noHeader1: true,
+		noHeader2: true,
+	}.getUserInput()
+
+	reader1 := csv.NewReader(strings.NewReader("1,a\n2,b\n3,c\n"))
+	reader2 := csv.NewReader(strings.NewReader("2,b\n3,c\n"))
+	res1, res2, err := csvcheckcli.GetResArraysSorted(reader1, reader2, input)
+
+	expected1 := Get2DArrayFromCsvString(fmt.Sprintf(`
+col1,col2,%s
+1,a,1
+`, csvcheckcli.IndexColumnName))
+
+	expected2 := Get2DArrayFromCsvString(fmt.Sprintf(`
+col1,col2,%s
+`, csvcheckcli.IndexColumnName))
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected1, res1)
+	assert.Equal(t, expected2, res2)
+}
+
+func TestGetResArraysSortedOutOfOrder(t *testing.T) {
+	input := userInputSolid{
+		inputDir:     "/path/to/input/dir",
+		files:        []string{"file1.csv", "file2.csv"},
+		method:       csvcheckcli.MethodStringSorted,
+		function:     csvcheckcli.FunctionStringCommon,
+		columnsToUse: []string{"id"},
+	}.getUserInput()
+
+	reader1 := csv.NewReader(strings.NewReader("id,v\n1,a\n2,b\n"))
+	reader2 := csv.NewReader(strings.NewReader("id,v\n1,a\n3,c\n2,b\n"))
+	_, _, err := csvcheckcli.GetResArraysSorted(reader1, reader2, input)
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "second file")
+	assert.Contains(t, err.Error(), "line 4")
+}
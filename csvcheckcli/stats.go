@@ -0,0 +1,219 @@
+package csvcheckcli
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+)
+
+// The maximum number of example keys listed for a column.
+const maxExampleKeys = 3
+
+// The columns of the statistics array.
+var statsColumns = []string{"column", "differing", "percent_differing", "delta_min", "delta_max", "delta_mean", "example_keys"}
+
+// For holding the difference statistics of a column over the rows paired by key.
+type ColumnStats struct {
+	Column           string   `json:"column"`
+	Differing        int      `json:"differing"`         // The number of paired rows where the values differ.
+	PercentDiffering float64  `json:"percent_differing"` // The percentage of paired rows where the values differ.
+	ExampleKeys      []string `json:"example_keys"`      // The keys of the first differing rows.
+	Numeric          bool     `json:"numeric"`           // Whether every non-empty value of the paired rows is a number.
+	DeltaMin         *float64 `json:"delta_min"`         // The smallest value in the second file minus the one in the first, nil if not numeric.
+	DeltaMax         *float64 `json:"delta_max"`
+	DeltaMean        *float64 `json:"delta_mean"`
+}
+
+// For holding the difference statistics of the rows of two csv arrays paired by key.
+type DifferenceStats struct {
+	KeyColumns []string      `json:"key_columns"`
+	Paired     int           `json:"paired"`    // The number of paired rows.
+	Unpaired1  int           `json:"unpaired1"` // The number of rows of the first csv without a pair.
+	Unpaired2  int           `json:"unpaired2"` // The number of rows of the second csv without a pair.
+	Columns    []ColumnStats `json:"columns"`
+}
+
+// Returns the value as a number and whether it is one. Surrounding whitespace is ignored.
+func parseNumber(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return v, err == nil && !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// Returns the number formatted for the statistics array.
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'g', 10, 64)
+}
+
+// Returns the rows of the csv arrays paired by the key columns. Rows with the same
+// key are paired in the order they appear in, like the match method does.
+func pairRowsByKey(csvArray1, csvArray2 [][]csvcheck.StringHashable, keyIndices1, keyIndices2 []int) ([][2]int, int, int) {
+	occurrences := make(map[string][]int)
+	for i := 1; i < len(csvArray1); i++ {
+		key := getKeyString(csvArray1[i], keyIndices1)
+		occurrences[key] = append(occurrences[key], i)
+	}
+
+	pairs := [][2]int{}
+	for i := 1; i < len(csvArray2); i++ {
+		key := getKeyString(csvArray2[i], keyIndices2)
+		if rows := occurrences[key]; len(rows) > 0 {
+			pairs = append(pairs, [2]int{rows[0], i})
+			occurrences[key] = rows[1:]
+		}
+	}
+	return pairs, len(csvArray1) - 1 - len(pairs), len(csvArray2) - 1 - len(pairs)
+}
+
+// Returns the difference statistics of the compared columns other than the key
+// columns, over the rows of the csv arrays paired by the key columns of the config.
+// Values are compared after the unicode normalization of the config, if any.
+func GetDifferenceStats(csvArray1, csvArray2 [][]csvcheck.StringHashable, cfg Config) (*DifferenceStats, error) {
+	csvArray1, csvArray2, err := prepareCsvArrays(csvArray1, csvArray2, cfg)
+	if err != nil {
+		return nil, err
+	}
+	normalizer, err := newValueNormalizer(cfg.UnicodeNormalize, cfg.StripAccents)
+	if err != nil {
+		return nil, err
+	}
+	if normalizer != nil {
+		csvArray1 = normalizeCsvArrayValues(csvArray1, normalizer)
+		csvArray2 = normalizeCsvArrayValues(csvArray2, normalizer)
+	}
+	header1, header2 := csvArray1[0], csvArray2[0]
+
+	columns, err := resolveInputColumns(header1, header2, cfg)
+	if err != nil {
+		return nil, err
+	}
+	keyColumns, err := resolveColumns("keycolumns", cfg.KeyColumns, cfg.NormalizeHeaders, header1, header2)
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range [][]csvcheck.StringHashable{header1, header2} {
+		err = checkColumnsExist("keycolumns", keyColumns, header)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, row := range csvArray1[1:] {
+		if len(row) != len(header1) {
+			return nil, fmt.Errorf("row %d of the first csv has %d columns but the header has %d", i+1, len(row), len(header1))
+		}
+	}
+	for i, row := range csvArray2[1:] {
+		if len(row) != len(header2) {
+			return nil, fmt.Errorf("row %d of the second csv has %d columns but the header has %d", i+1, len(row), len(header2))
+		}
+	}
+
+	keyIndices1 := getColumnIndices(header1, keyColumns)
+	keyIndices2 := getColumnIndices(header2, keyColumns)
+	pairs, unpaired1, unpaired2 := pairRowsByKey(csvArray1, csvArray2, keyIndices1, keyIndices2)
+
+	stats := &DifferenceStats{
+		KeyColumns: getRowStrings(keyColumns),
+		Paired:     len(pairs),
+		Unpaired1:  unpaired1,
+		Unpaired2:  unpaired2,
+		Columns:    []ColumnStats{},
+	}
+
+	isKey := make(map[string]bool)
+	for _, column := range keyColumns {
+		isKey[column.StringHash()] = true
+	}
+	positions2 := make(map[string]int)
+	for i, column := range header2 {
+		positions2[column.StringHash()] = i
+	}
+	for _, i := range getComparedIndices(header1, columns.toUse, columns.toIgnore) {
+		name := header1[i].StringHash()
+		j, inBoth := positions2[name]
+		if isKey[name] || !inBoth {
+			continue
+		}
+		stats.Columns = append(stats.Columns, getColumnStats(name, csvArray1, csvArray2, i, j, keyIndices1, pairs))
+	}
+	return stats, nil
+}
+
+// Returns the difference statistics of the column at index i of the first csv
+// array and index j of the second over the paired rows.
+func getColumnStats(name string, csvArray1, csvArray2 [][]csvcheck.StringHashable, i, j int, keyIndices1 []int, pairs [][2]int) ColumnStats {
+	stats := ColumnStats{Column: name, ExampleKeys: []string{}, Numeric: true}
+	deltaCount := 0
+	deltaMin, deltaMax, deltaSum := math.Inf(1), math.Inf(-1), 0.0
+	for _, pair := range pairs {
+		row1, row2 := csvArray1[pair[0]], csvArray2[pair[1]]
+		value1, value2 := row1[i].StringHash(), row2[j].StringHash()
+		if value1 != value2 {
+			stats.Differing++
+			if len(stats.ExampleKeys) < maxExampleKeys {
+				key := make([]string, len(keyIndices1))
+				for k, index := range keyIndices1 {
+					key[k] = row1[index].StringHash()
+				}
+				stats.ExampleKeys = append(stats.ExampleKeys, strings.Join(key, ","))
+			}
+		}
+
+		if !stats.Numeric || strings.TrimSpace(value1) == "" || strings.TrimSpace(value2) == "" {
+			continue
+		}
+		number1, isNumber1 := parseNumber(value1)
+		number2, isNumber2 := parseNumber(value2)
+		if !isNumber1 || !isNumber2 {
+			stats.Numeric = false
+			continue
+		}
+		delta := number2 - number1
+		deltaMin = min(deltaMin, delta)
+		deltaMax = max(deltaMax, delta)
+		deltaSum += delta
+		deltaCount++
+	}
+
+	if len(pairs) > 0 {
+		stats.PercentDiffering = float64(stats.Differing) * 100 / float64(len(pairs))
+	}
+	stats.Numeric = stats.Numeric && deltaCount > 0
+	if stats.Numeric {
+		deltaMean := deltaSum / float64(deltaCount)
+		stats.DeltaMin, stats.DeltaMax, stats.DeltaMean = &deltaMin, &deltaMax, &deltaMean
+	}
+	return stats
+}
+
+// Returns the column statistics as a csv array with the columns row. The deltas are
+// left empty for columns that are not numeric.
+func GetStatsArray(stats *DifferenceStats) [][]csvcheck.StringHashable {
+	res := [][]csvcheck.StringHashable{csvcheck.GetRowFromRow(statsColumns)}
+	for _, column := range stats.Columns {
+		deltaMin, deltaMax, deltaMean := "", "", ""
+		if column.Numeric {
+			deltaMin = formatNumber(*column.DeltaMin)
+			deltaMax = formatNumber(*column.DeltaMax)
+			deltaMean = formatNumber(*column.DeltaMean)
+		}
+		res = append(res, csvcheck.GetRowFromRow([]string{
+			column.Column,
+			strconv.Itoa(column.Differing),
+			strconv.FormatFloat(column.PercentDiffering, 'f', 2, 64),
+			deltaMin,
+			deltaMax,
+			deltaMean,
+			strings.Join(column.ExampleKeys, "; "),
+		}))
+	}
+	return res
+}
+
+// Returns a line summarizing how the rows were paired.
+func FormatStatsSummary(stats *DifferenceStats) string {
+	return fmt.Sprintf("Paired rows by %s: %s, only in the first file: %s, only in the second file: %s\n",
+		strings.Join(stats.KeyColumns, ", "), formatThousands(stats.Paired), formatThousands(stats.Unpaired1), formatThousands(stats.Unpaired2))
+}
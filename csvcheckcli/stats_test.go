@@ -0,0 +1,134 @@
+package csvcheckcli_test
+
+import (
+	"context"
+	"csvcheckcli/csvcheckcli"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDifferenceStats(t *testing.T) {
+	csvArray1 := Get2DArrayFromCsvString(`
+id,name,amount,note
+1,a,10,x
+2,b,20,y
+3,c,30,z
+5,e,1,q
+`)
+	csvArray2 := Get2DArrayFromCsvString(`
+amount,id,name,note
+10,1,a,x
+25,2,b,y
+27.5,3,cc,
+40,4,d,w
+`)
+
+	cfg := csvcheckcli.NewConfig(
+		csvcheckcli.WithFunction(csvcheckcli.FunctionStringStats),
+		csvcheckcli.WithKeyColumns("id"),
+	)
+	stats, err := csvcheckcli.GetDifferenceStats(csvArray1, csvArray2, cfg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id"}, stats.KeyColumns)
+	assert.Equal(t, 3, stats.Paired)
+	assert.Equal(t, 1, stats.Unpaired1)
+	assert.Equal(t, 1, stats.Unpaired2)
+	assert.Equal(t, Get2DArrayFromCsvString(`
+column,differing,percent_differing,delta_min,delta_max,delta_mean,example_keys
+name,1,33.33,,,,3
+amount,2,66.67,-2.5,5,0.8333333333,2; 3
+note,1,33.33,,,,3
+`), csvcheckcli.GetStatsArray(stats))
+
+	cfg.ColumnsToUse = []string{"amount"}
+	stats, err = csvcheckcli.GetDifferenceStats(csvArray1, csvArray2, cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(stats.Columns))
+	assert.True(t, stats.Columns[0].Numeric)
+	assert.Equal(t, 2.5/3, *stats.Columns[0].DeltaMean)
+}
+
+func TestGetDifferenceStatsDuplicateKeysPairInOrder(t *testing.T) {
+	csvArray1 := Get2DArrayFromCsvString(`
+id,a
+1,x
+1,y
+`)
+	csvArray2 := Get2DArrayFromCsvString(`
+id,a
+1,x
+1,z
+1,w
+`)
+
+	cfg := csvcheckcli.NewConfig(
+		csvcheckcli.WithFunction(csvcheckcli.FunctionStringStats),
+		csvcheckcli.WithKeyColumns("id"),
+	)
+	stats, err := csvcheckcli.GetDifferenceStats(csvArray1, csvArray2, cfg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, stats.Paired)
+	assert.Equal(t, 0, stats.Unpaired1)
+	assert.Equal(t, 1, stats.Unpaired2)
+	assert.Equal(t, 1, stats.Columns[0].Differing)
+	assert.False(t, stats.Columns[0].Numeric)
+}
+
+func TestStatsArrayWithMultipleKeysReadsBackAsCsv(t *testing.T) {
+	csvArray1 := Get2DArrayFromCsvString(`
+id,part,amount
+1,a,10
+2,b,20
+`)
+	csvArray2 := Get2DArrayFromCsvString(`
+id,part,amount
+1,a,11
+2,b,21
+`)
+
+	cfg := csvcheckcli.NewConfig(
+		csvcheckcli.WithFunction(csvcheckcli.FunctionStringStats),
+		csvcheckcli.WithKeyColumns("id", "part"),
+	)
+	stats, err := csvcheckcli.GetDifferenceStats(csvArray1, csvArray2, cfg)
+	assert.Nil(t, err)
+
+	statsString, err := csvcheckcli.FormatCsvArray(csvcheckcli.GetStatsArray(stats))
+	assert.Nil(t, err)
+	records, err := csv.NewReader(strings.NewReader(statsString)).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"column", "differing", "percent_differing", "delta_min", "delta_max", "delta_mean", "example_keys"},
+		{"amount", "2", "100.00", "1", "1", "1", "1,a; 2,b"},
+	}, records)
+}
+
+func TestCompareStatsProperAndImproperInputs(t *testing.T) {
+	for i, data := range []struct {
+		options     []csvcheckcli.Option
+		expectError bool
+	}{
+		{options: []csvcheckcli.Option{csvcheckcli.WithKeyColumns("id")}, expectError: false},
+		{options: []csvcheckcli.Option{csvcheckcli.WithKeyColumns("#1")}, expectError: false},
+		{options: []csvcheckcli.Option{}, expectError: true},
+		{options: []csvcheckcli.Option{csvcheckcli.WithKeyColumns("ids")}, expectError: true},
+		{options: []csvcheckcli.Option{csvcheckcli.WithKeyColumns("id"), csvcheckcli.WithMethod(csvcheckcli.MethodStringSorted)}, expectError: true},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		cfg := csvcheckcli.NewConfig(append(data.options, csvcheckcli.WithFunction(csvcheckcli.FunctionStringStats))...)
+		result, err := csvcheckcli.Compare(context.Background(), strings.NewReader("id,a\n1,2\n"), strings.NewReader("id,a\n1,3\n"), cfg)
+		if data.expectError {
+			assert.NotNil(t, err, indexString)
+		} else {
+			assert.Nil(t, err, indexString)
+			assert.Equal(t, 1, result.Stats.Columns[0].Differing, indexString)
+			assert.Nil(t, result.Rows1, indexString)
+		}
+	}
+}
@@ -0,0 +1,609 @@
+package csvcheckcli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+	"golang.org/x/term"
+	"golang.org/x/text/width"
+)
+
+// The keys of the result browser other than printable characters, which are
+// given as the character itself.
+const (
+	KeyUp        = "up"
+	KeyDown      = "down"
+	KeyLeft      = "left"
+	KeyRight     = "right"
+	KeyPageUp    = "pgup"
+	KeyPageDown  = "pgdown"
+	KeyHome      = "home"
+	KeyEnd       = "end"
+	KeyTab       = "tab"
+	KeyEnter     = "enter"
+	KeyEscape    = "esc"
+	KeyBackspace = "backspace"
+	KeyCtrlC     = "ctrl+c"
+)
+
+// The escape sequences of the keys, as sent by common terminals.
+var keySequences = map[string]string{
+	"\x1b[A":  KeyUp,
+	"\x1bOA":  KeyUp,
+	"\x1b[B":  KeyDown,
+	"\x1bOB":  KeyDown,
+	"\x1b[C":  KeyRight,
+	"\x1bOC":  KeyRight,
+	"\x1b[D":  KeyLeft,
+	"\x1bOD":  KeyLeft,
+	"\x1b[5~": KeyPageUp,
+	"\x1b[6~": KeyPageDown,
+	"\x1b[H":  KeyHome,
+	"\x1bOH":  KeyHome,
+	"\x1b[1~": KeyHome,
+	"\x1b[F":  KeyEnd,
+	"\x1bOF":  KeyEnd,
+	"\x1b[4~": KeyEnd,
+}
+
+// The ANSI escape sequences used for drawing.
+const (
+	ansiReset     = "\x1b[0m"
+	ansiReverse   = "\x1b[7m"
+	ansiBold      = "\x1b[1m"
+	ansiRed       = "\x1b[1;31m"
+	ansiUnderline = "\x1b[4m"
+)
+
+// The widest a column is drawn in the result browser.
+const browserMaxColumnWidth = 30
+
+// The help shown in the status line of the result browser.
+const browserHelp = "arrows move  tab pane  / search  n/N next/prev  i jump to " + IndexColumnName + "  x hide column  u unhide  q quit"
+
+// The modes of the result browser, which are either browsing or reading the text of a command.
+const (
+	browserModeNormal = iota
+	browserModeSearch
+	browserModeJump
+)
+
+// Returns the keys in the input read from a terminal in raw mode.
+func ParseKeys(input []byte) []string {
+	keys := []string{}
+	for len(input) > 0 {
+		if input[0] == 0x1b {
+			matched := false
+			for sequence, key := range keySequences {
+				if strings.HasPrefix(string(input), sequence) {
+					keys = append(keys, key)
+					input = input[len(sequence):]
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				keys = append(keys, KeyEscape)
+				input = input[1:]
+			}
+			continue
+		}
+
+		switch input[0] {
+		case '\t':
+			keys = append(keys, KeyTab)
+		case '\r', '\n':
+			keys = append(keys, KeyEnter)
+		case 0x7f, 0x08:
+			keys = append(keys, KeyBackspace)
+		case 0x03:
+			keys = append(keys, KeyCtrlC)
+		default:
+			r, size := utf8.DecodeRune(input)
+			if r >= ' ' {
+				keys = append(keys, string(r))
+			}
+			input = input[size:]
+			continue
+		}
+		input = input[1:]
+	}
+	return keys
+}
+
+// For holding one side of the result browser.
+type browserPane struct {
+	title       string
+	arr         [][]csvcheck.StringHashable
+	cursor      int // The selected row, 0 being the first row below the header.
+	top         int // The first row shown.
+	column      int // The selected column among the visible ones.
+	firstColumn int // The first visible column shown.
+	keyIndices  []int
+	keys        map[string]int // The first row of each key, if rows are matched by key.
+}
+
+// Returns the number of rows below the header.
+func (p *browserPane) rowCount() int {
+	return len(p.arr) - 1
+}
+
+// Returns the value of the column of the row, which is 0-based below the header.
+func (p *browserPane) value(row, column int) string {
+	if column >= len(p.arr[row+1]) {
+		return ""
+	}
+	return p.arr[row+1][column].StringHash()
+}
+
+// An interactive browser of the results of both files shown side by side. It holds
+// no terminal state, so it can be driven by HandleKey and drawn by Render anywhere.
+type Browser struct {
+	panes   [2]*browserPane
+	focus   int
+	hidden  map[string]bool
+	width   int
+	height  int
+	mode    int
+	input   string
+	search  string
+	message string
+}
+
+// Returns a browser of the result arrays titled by the file names. If key columns are
+// given and both results have them, the row of the other file with the key of the
+// selected row is selected alongside it and the cells that differ are highlighted.
+func NewBrowser(title1, title2 string, res1, res2 [][]csvcheck.StringHashable, keyColumns []string) *Browser {
+	b := &Browser{hidden: make(map[string]bool), width: 80, height: 24}
+	for i, arr := range [][][]csvcheck.StringHashable{res1, res2} {
+		if len(arr) == 0 {
+			arr = [][]csvcheck.StringHashable{{}}
+		}
+		b.panes[i] = &browserPane{title: []string{title1, title2}[i], arr: arr}
+	}
+
+	columns := csvcheck.GetRowFromRow(keyColumns)
+	if len(keyColumns) == 0 || checkColumnsExist("keycolumns", columns, b.panes[0].arr[0]) != nil || checkColumnsExist("keycolumns", columns, b.panes[1].arr[0]) != nil {
+		return b
+	}
+	for _, p := range b.panes {
+		p.keyIndices = getColumnIndices(p.arr[0], columns)
+		p.keys = make(map[string]int)
+		for row := p.rowCount() - 1; row >= 0; row-- {
+			p.keys[getKeyString(p.arr[row+1], p.keyIndices)] = row
+		}
+	}
+	b.syncOtherPane()
+	return b
+}
+
+// Sets the size of the screen the browser is drawn on.
+func (b *Browser) Resize(width, height int) {
+	b.width = max(width, 20)
+	b.height = max(height, 5)
+	for _, p := range b.panes {
+		b.moveCursor(p, p.cursor)
+	}
+}
+
+// Returns the number of result rows shown in each pane.
+func (b *Browser) pageSize() int {
+	// The title, header and status lines are not result rows.
+	return b.height - 3
+}
+
+// Returns the positions of the columns of the pane that are not hidden.
+func (b *Browser) visibleColumns(p *browserPane) []int {
+	res := []int{}
+	for i, column := range p.arr[0] {
+		if !b.hidden[column.StringHash()] {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+// Returns the row of the other pane with the key of the selected row of the
+// focused pane, or -1 if rows are not matched by key or there is none.
+func (b *Browser) matchedRow() int {
+	p, other := b.panes[b.focus], b.panes[1-b.focus]
+	if p.keys == nil || p.rowCount() == 0 {
+		return -1
+	}
+	row, exists := other.keys[getKeyString(p.arr[p.cursor+1], p.keyIndices)]
+	if !exists {
+		return -1
+	}
+	return row
+}
+
+// Selects the row of the other pane matching the selected row of the focused pane.
+func (b *Browser) syncOtherPane() {
+	if row := b.matchedRow(); row >= 0 {
+		b.moveCursor(b.panes[1-b.focus], row)
+	}
+}
+
+// Selects the row of the pane, kept within its rows, and scrolls it into view.
+func (b *Browser) moveCursor(p *browserPane, row int) {
+	p.cursor = max(min(row, p.rowCount()-1), 0)
+	if p.cursor < p.top {
+		p.top = p.cursor
+	}
+	if p.cursor >= p.top+b.pageSize() {
+		p.top = p.cursor - b.pageSize() + 1
+	}
+}
+
+// Moves the selection to the next (or previous if backwards is true) row of the
+// focused pane containing the search text, ignoring case.
+func (b *Browser) findNext(backwards bool) {
+	if b.search == "" {
+		return
+	}
+	p := b.panes[b.focus]
+	search := strings.ToLower(b.search)
+	step := 1
+	if backwards {
+		step = -1
+	}
+	for i := 1; i <= p.rowCount(); i++ {
+		row := ((p.cursor+i*step)%p.rowCount() + p.rowCount()) % p.rowCount()
+		for _, column := range b.visibleColumns(p) {
+			if strings.Contains(strings.ToLower(p.value(row, column)), search) {
+				b.moveCursor(p, row)
+				b.syncOtherPane()
+				return
+			}
+		}
+	}
+	b.message = fmt.Sprintf("%q not found", b.search)
+}
+
+// Moves the selection of the focused pane to the row with the index.
+func (b *Browser) jumpToIndex(index string) {
+	p := b.panes[b.focus]
+	position := -1
+	for i, column := range p.arr[0] {
+		if column.StringHash() == IndexColumnName {
+			position = i
+		}
+	}
+	if position < 0 {
+		b.message = fmt.Sprintf("the results have no %s column, use --keepindex", IndexColumnName)
+		return
+	}
+	for row := 0; row < p.rowCount(); row++ {
+		if p.value(row, position) == index {
+			b.moveCursor(p, row)
+			b.syncOtherPane()
+			return
+		}
+	}
+	b.message = fmt.Sprintf("no row with %s %s", IndexColumnName, index)
+}
+
+// Hides the selected column of the focused pane in both panes.
+func (b *Browser) hideColumn() {
+	p := b.panes[b.focus]
+	visible := b.visibleColumns(p)
+	if len(visible) <= 1 {
+		b.message = "the last column cannot be hidden"
+		return
+	}
+	b.hidden[p.arr[0][visible[p.column]].StringHash()] = true
+	for _, p := range b.panes {
+		p.column = max(min(p.column, len(b.visibleColumns(p))-1), 0)
+		p.firstColumn = min(p.firstColumn, p.column)
+	}
+}
+
+// Handles a key and returns true iff the browser should be closed.
+func (b *Browser) HandleKey(key string) bool {
+	b.message = ""
+	if b.mode != browserModeNormal {
+		switch key {
+		case KeyEnter:
+			if b.mode == browserModeSearch {
+				b.search = b.input
+				b.findNext(false)
+			} else {
+				b.jumpToIndex(strings.TrimSpace(b.input))
+			}
+			b.mode = browserModeNormal
+		case KeyEscape, KeyCtrlC:
+			b.mode = browserModeNormal
+		case KeyBackspace:
+			if len(b.input) > 0 {
+				_, size := utf8.DecodeLastRuneInString(b.input)
+				b.input = b.input[:len(b.input)-size]
+			}
+		default:
+			if utf8.RuneCountInString(key) == 1 {
+				b.input += key
+			}
+		}
+		return false
+	}
+
+	p := b.panes[b.focus]
+	switch key {
+	case "q", KeyCtrlC:
+		return true
+	case KeyUp, "k":
+		b.moveCursor(p, p.cursor-1)
+		b.syncOtherPane()
+	case KeyDown, "j":
+		b.moveCursor(p, p.cursor+1)
+		b.syncOtherPane()
+	case KeyPageUp:
+		b.moveCursor(p, p.cursor-b.pageSize())
+		b.syncOtherPane()
+	case KeyPageDown, " ":
+		b.moveCursor(p, p.cursor+b.pageSize())
+		b.syncOtherPane()
+	case KeyHome, "g":
+		b.moveCursor(p, 0)
+		b.syncOtherPane()
+	case KeyEnd, "G":
+		b.moveCursor(p, p.rowCount()-1)
+		b.syncOtherPane()
+	case KeyLeft, "h":
+		p.column = max(p.column-1, 0)
+	case KeyRight, "l":
+		p.column = max(min(p.column+1, len(b.visibleColumns(p))-1), 0)
+	case KeyTab:
+		b.focus = 1 - b.focus
+		b.syncOtherPane()
+	case "/":
+		b.mode, b.input = browserModeSearch, ""
+	case "n":
+		b.findNext(false)
+	case "N":
+		b.findNext(true)
+	case "i":
+		b.mode, b.input = browserModeJump, ""
+	case "x":
+		b.hideColumn()
+	case "u":
+		b.hidden = make(map[string]bool)
+	}
+	return false
+}
+
+// Returns the text with control characters, such as escape sequences and line breaks,
+// replaced by their Go escapes, so that values cannot move the cursor or restyle the screen.
+func escapeText(s string) string {
+	if strings.IndexFunc(s, unicode.IsControl) < 0 {
+		return s
+	}
+	var builder strings.Builder
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			quoted := strconv.QuoteRune(r)
+			builder.WriteString(quoted[1 : len(quoted)-1])
+		} else {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// Returns the number of terminal columns the rune takes up: 2 for wide east asian
+// characters, 0 for combining marks and 1 otherwise. Characters whose width depends
+// on the terminal, such as emoji, are taken as 1 wide.
+func runeWidth(r rune) int {
+	if unicode.In(r, unicode.Mn, unicode.Me) {
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+// Returns the number of terminal columns the text takes up once escaped.
+func textWidth(s string) int {
+	res := 0
+	for _, r := range escapeText(s) {
+		res += runeWidth(r)
+	}
+	return res
+}
+
+// Returns the text escaped and cut or padded with spaces to the width in terminal columns.
+func fitText(s string, columns int) string {
+	s = escapeText(s)
+	length := textWidth(s)
+	if length <= columns {
+		return s + strings.Repeat(" ", columns-length)
+	}
+
+	mark := ""
+	if columns > len(csvcheck.TruncatedMark) {
+		mark = csvcheck.TruncatedMark
+	}
+	var builder strings.Builder
+	used := 0
+	for _, r := range s {
+		if used+runeWidth(r) > columns-len(mark) {
+			break
+		}
+		builder.WriteRune(r)
+		used += runeWidth(r)
+	}
+	// A wide character that does not fit is left out, so pad to the width.
+	builder.WriteString(strings.Repeat(" ", columns-len(mark)-used))
+	builder.WriteString(mark)
+	return builder.String()
+}
+
+// Returns the lines of the pane, each the given width in terminal columns. differing holds
+// the names of the columns whose values differ between the selected and matched rows,
+// which are highlighted in the row highlighted.
+func (b *Browser) renderPane(p *browserPane, width int, focused bool, highlighted int, differing map[string]bool) []string {
+	visible := b.visibleColumns(p)
+	widths := make([]int, len(visible))
+	for i, column := range visible {
+		widths[i] = min(textWidth(p.arr[0][column].StringHash()), browserMaxColumnWidth)
+		for row := p.top; row < min(p.top+b.pageSize(), p.rowCount()); row++ {
+			widths[i] = max(widths[i], min(textWidth(p.value(row, column)), browserMaxColumnWidth))
+		}
+	}
+
+	// Scroll the columns so that the selected one is shown.
+	p.firstColumn = min(p.firstColumn, p.column)
+	for p.firstColumn < p.column {
+		used := 0
+		for i := p.firstColumn; i <= p.column; i++ {
+			used += widths[i] + 2
+		}
+		if used <= width {
+			break
+		}
+		p.firstColumn++
+	}
+
+	renderRow := func(values func(column int) string, row int) string {
+		var builder strings.Builder
+		used := 0
+		for i := p.firstColumn; i < len(visible) && used < width; i++ {
+			cell := fitText(values(visible[i]), min(widths[i], width-used))
+			style := ""
+			switch {
+			case row < 0 && i == p.column && focused:
+				style = ansiBold + ansiUnderline
+			case row < 0:
+				style = ansiBold
+			case row == highlighted && differing[p.arr[0][visible[i]].StringHash()]:
+				style = ansiRed + ansiReverse
+			case row == highlighted && i == p.column && focused:
+				style = ansiReverse + ansiBold
+			case row == highlighted:
+				style = ansiReverse
+			}
+			used += textWidth(cell)
+			if style != "" {
+				cell = style + cell + ansiReset
+			}
+			builder.WriteString(cell)
+			if used < width {
+				separator := fitText("", min(2, width-used))
+				builder.WriteString(separator)
+				used += len(separator)
+			}
+		}
+		builder.WriteString(strings.Repeat(" ", max(width-used, 0)))
+		return builder.String()
+	}
+
+	title := fmt.Sprintf("%s (%s rows)", p.title, formatThousands(p.rowCount()))
+	if p.rowCount() > 0 {
+		title = fmt.Sprintf("%s (row %s of %s)", p.title, formatThousands(p.cursor+1), formatThousands(p.rowCount()))
+	}
+	title = fitText(title, width)
+	if focused {
+		title = ansiReverse + title + ansiReset
+	}
+	lines := []string{title}
+	lines = append(lines, renderRow(func(column int) string { return p.arr[0][column].StringHash() }, -1))
+	for row := p.top; row < p.top+b.pageSize(); row++ {
+		if row >= p.rowCount() {
+			lines = append(lines, strings.Repeat(" ", width))
+			continue
+		}
+		lines = append(lines, renderRow(func(column int) string { return p.value(row, column) }, row))
+	}
+	return lines
+}
+
+// Returns the screen of the browser as text with ANSI escape sequences, starting at
+// the top left corner of the terminal.
+func (b *Browser) Render() string {
+	focused, other := b.panes[b.focus], b.panes[1-b.focus]
+	differing := make(map[string]bool)
+	matched := b.matchedRow()
+	if matched >= 0 {
+		values := make(map[string]string)
+		for i, column := range other.arr[0] {
+			values[column.StringHash()] = other.value(matched, i)
+		}
+		for i, column := range focused.arr[0] {
+			if value, exists := values[column.StringHash()]; exists && value != focused.value(focused.cursor, i) {
+				differing[column.StringHash()] = true
+			}
+		}
+	}
+
+	paneWidth := (b.width - 3) / 2
+	highlighted := [2]int{-1, -1}
+	highlighted[b.focus] = focused.cursor
+	highlighted[1-b.focus] = matched
+	lines1 := b.renderPane(b.panes[0], paneWidth, b.focus == 0, highlighted[0], differing)
+	lines2 := b.renderPane(b.panes[1], b.width-3-paneWidth, b.focus == 1, highlighted[1], differing)
+
+	var builder strings.Builder
+	builder.WriteString("\x1b[H")
+	for i := range lines1 {
+		builder.WriteString(lines1[i] + " │ " + lines2[i] + "\r\n")
+	}
+
+	status := browserHelp
+	switch {
+	case b.mode == browserModeSearch:
+		status = "/" + b.input
+	case b.mode == browserModeJump:
+		status = IndexColumnName + ": " + b.input
+	case b.message != "":
+		status = b.message
+	}
+	builder.WriteString(fitText(status, b.width))
+	return builder.String()
+}
+
+// Runs the browser on the terminal of in and out until it is closed. The terminal
+// is put in raw mode and the alternate screen is used, and both are restored after.
+func RunBrowser(b *Browser, in, out *os.File) error {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return err
+	}
+	defer term.Restore(int(in.Fd()), state)
+
+	io.WriteString(out, "\x1b[?1049h\x1b[?25l")
+	defer io.WriteString(out, "\x1b[?25h\x1b[?1049l")
+
+	buffer := make([]byte, 256)
+	for {
+		width, height, err := term.GetSize(int(out.Fd()))
+		if err == nil {
+			b.Resize(width, height)
+		}
+		_, err = io.WriteString(out, b.Render())
+		if err != nil {
+			return err
+		}
+
+		n, err := in.Read(buffer)
+		if err != nil {
+			return err
+		}
+		for _, key := range ParseKeys(buffer[:n]) {
+			if b.HandleKey(key) {
+				return nil
+			}
+		}
+	}
+}
+
+// Returns true iff both files are terminals, which the browser needs to run.
+func IsTerminal(in, out *os.File) bool {
+	return term.IsTerminal(int(in.Fd())) && term.IsTerminal(int(out.Fd()))
+}
@@ -0,0 +1,165 @@
+package csvcheckcli_test
+
+import (
+	"csvcheckcli/csvcheckcli"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+	"github.com/stretchr/testify/assert"
+)
+
+var ansiPattern = regexp.MustCompile("\x1b\\[[0-9;?]*[A-Za-z]")
+
+// Returns the lines of the rendered browser without the escape sequences.
+func renderBrowserText(b *csvcheckcli.Browser) []string {
+	return strings.Split(ansiPattern.ReplaceAllString(b.Render(), ""), "\r\n")
+}
+
+func newTestBrowser(keyColumns []string) *csvcheckcli.Browser {
+	res1 := Get2DArrayFromCsvString(`
+id,name,amount,_ind
+1,alice,10,1
+2,bob,20,2
+3,carol,30,3
+`)
+	res2 := Get2DArrayFromCsvString(`
+id,name,amount,_ind
+3,carol,35,1
+1,alice,10,4
+`)
+	b := csvcheckcli.NewBrowser("x.csv", "y.csv", res1, res2, keyColumns)
+	b.Resize(100, 10)
+	return b
+}
+
+func TestParseKeys(t *testing.T) {
+	for i, data := range []struct {
+		input    string
+		expected []string
+	}{
+		{input: "q", expected: []string{"q"}},
+		{input: "\x1b[A\x1b[B\x1bOC\x1b[D", expected: []string{csvcheckcli.KeyUp, csvcheckcli.KeyDown, csvcheckcli.KeyRight, csvcheckcli.KeyLeft}},
+		{input: "\x1b[5~\x1b[6~\x1b[H\x1b[4~", expected: []string{csvcheckcli.KeyPageUp, csvcheckcli.KeyPageDown, csvcheckcli.KeyHome, csvcheckcli.KeyEnd}},
+		{input: "\t\r\x7f\x03\x1b", expected: []string{csvcheckcli.KeyTab, csvcheckcli.KeyEnter, csvcheckcli.KeyBackspace, csvcheckcli.KeyCtrlC, csvcheckcli.KeyEscape}},
+		{input: "/é1", expected: []string{"/", "é", "1"}},
+	} {
+		indexString := fmt.Sprintf("Test case index: %d", i)
+		assert.Equal(t, data.expected, csvcheckcli.ParseKeys([]byte(data.input)), indexString)
+	}
+}
+
+func TestBrowserRender(t *testing.T) {
+	b := newTestBrowser(nil)
+
+	lines := renderBrowserText(b)
+
+	assert.Len(t, lines, 10)
+	assert.Contains(t, lines[0], "x.csv (row 1 of 3)")
+	assert.Contains(t, lines[0], "y.csv (row 1 of 2)")
+	assert.Contains(t, lines[1], "id  name   amount  _ind")
+	assert.Contains(t, lines[4], "3   carol  30      3")
+	assert.Contains(t, lines[9], "q quit")
+}
+
+func TestBrowserNavigationWithKeyColumns(t *testing.T) {
+	b := newTestBrowser([]string{"id"})
+
+	assert.False(t, b.HandleKey(csvcheckcli.KeyDown))
+	lines := renderBrowserText(b)
+	assert.Contains(t, lines[0], "x.csv (row 2 of 3)")
+	// bob has no row with its key in the other file, which keeps its selection.
+	assert.Contains(t, lines[0], "y.csv (row 2 of 2)")
+
+	b.HandleKey(csvcheckcli.KeyDown)
+	lines = renderBrowserText(b)
+	assert.Contains(t, lines[0], "x.csv (row 3 of 3)")
+	assert.Contains(t, lines[0], "y.csv (row 1 of 2)")
+	// The amounts of carol differ, so they are highlighted in both files.
+	assert.Contains(t, b.Render(), "\x1b[1;31m\x1b[7m30")
+	assert.Contains(t, b.Render(), "\x1b[1;31m\x1b[7m35")
+
+	b.HandleKey(csvcheckcli.KeyTab)
+	b.HandleKey(csvcheckcli.KeyDown)
+	lines = renderBrowserText(b)
+	assert.Contains(t, lines[0], "x.csv (row 1 of 3)")
+	assert.Contains(t, lines[0], "y.csv (row 2 of 2)")
+	assert.True(t, b.HandleKey("q"))
+}
+
+func TestBrowserSearchAndJump(t *testing.T) {
+	b := newTestBrowser(nil)
+
+	for _, key := range []string{"/", "C", "A", "R", csvcheckcli.KeyEnter} {
+		b.HandleKey(key)
+	}
+	assert.Contains(t, renderBrowserText(b)[0], "x.csv (row 3 of 3)")
+
+	for _, key := range []string{"/", "z", "z", csvcheckcli.KeyEnter} {
+		b.HandleKey(key)
+	}
+	assert.Contains(t, renderBrowserText(b)[9], `"zz" not found`)
+
+	for _, key := range []string{"i", "2", csvcheckcli.KeyEnter} {
+		b.HandleKey(key)
+	}
+	assert.Contains(t, renderBrowserText(b)[0], "x.csv (row 2 of 3)")
+
+	for _, key := range []string{"i", "9", csvcheckcli.KeyEnter} {
+		b.HandleKey(key)
+	}
+	assert.Contains(t, renderBrowserText(b)[9], "no row with _ind 9")
+
+	b.HandleKey("i")
+	b.HandleKey("1")
+	assert.Contains(t, renderBrowserText(b)[9], "_ind: 1")
+	b.HandleKey(csvcheckcli.KeyEscape)
+	assert.Contains(t, renderBrowserText(b)[0], "x.csv (row 2 of 3)")
+}
+
+func TestBrowserHideColumns(t *testing.T) {
+	b := newTestBrowser(nil)
+
+	b.HandleKey(csvcheckcli.KeyRight)
+	b.HandleKey("x")
+	lines := renderBrowserText(b)
+	assert.Contains(t, lines[1], "id  amount  _ind")
+	assert.NotContains(t, lines[1], "name")
+
+	b.HandleKey("u")
+	assert.Contains(t, renderBrowserText(b)[1], "id  name   amount  _ind")
+}
+
+func TestBrowserRenderEscapesControlCharactersAndCountsWideCharacters(t *testing.T) {
+	res1 := [][]csvcheck.StringHashable{
+		csvcheck.GetRowFromRow([]string{"id", "note"}),
+		csvcheck.GetRowFromRow([]string{"1", "\x1b[2Jcleared"}),
+		csvcheck.GetRowFromRow([]string{"2", "a\r\nb"}),
+		csvcheck.GetRowFromRow([]string{"宽宽", "x"}),
+	}
+	b := csvcheckcli.NewBrowser("x.csv", "y.csv", res1, Get2DArrayFromCsvString("id\n1\n"), nil)
+	b.Resize(60, 8)
+
+	render := b.Render()
+	assert.NotContains(t, render, "\x1b[2J")
+	lines := renderBrowserText(b)
+	assert.Len(t, lines, 8)
+	assert.Contains(t, lines[1], "id    note")
+	assert.Contains(t, lines[2], `1     \x1b[2Jcleared`)
+	assert.Contains(t, lines[3], `2     a\r\nb`)
+	assert.Contains(t, lines[4], "宽宽  x")
+}
+
+func TestBrowserEmptyResults(t *testing.T) {
+	b := csvcheckcli.NewBrowser("x.csv", "y.csv", Get2DArrayFromCsvString("a,b\n"), Get2DArrayFromCsvString("a\n"), []string{"a"})
+	b.Resize(60, 8)
+
+	for _, key := range []string{csvcheckcli.KeyDown, csvcheckcli.KeyEnd, csvcheckcli.KeyTab, csvcheckcli.KeyRight, "x", "n", "/", "a", csvcheckcli.KeyEnter} {
+		assert.False(t, b.HandleKey(key))
+	}
+	lines := renderBrowserText(b)
+	assert.Contains(t, lines[0], "x.csv (0 rows)")
+	assert.Contains(t, lines[0], "y.csv (0 rows)")
+}
@@ -0,0 +1,189 @@
+package csvcheckcli
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+)
+
+// The interval at which watched files are checked for changes when none is given.
+const DefaultWatchInterval = time.Second
+
+// The maximum number of added or removed rows listed for each file in a result delta.
+const maxDeltaRows = 10
+
+// For holding what a watched file looked like when it was last checked.
+type fileState struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+// Returns the states of the files. Files that do not exist are not an error, as they
+// may be in the middle of being replaced.
+func getFileStates(paths []string) ([]fileState, error) {
+	res := make([]fileState, len(paths))
+	for i, path := range paths {
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		res[i] = fileState{exists: true, modTime: info.ModTime(), size: info.Size()}
+	}
+	return res, nil
+}
+
+// Returns true iff the states are the same.
+func fileStatesEqual(states1, states2 []fileState) bool {
+	for i := range states1 {
+		if states1[i].exists != states2[i].exists || !states1[i].modTime.Equal(states2[i].modTime) || states1[i].size != states2[i].size {
+			return false
+		}
+	}
+	return true
+}
+
+// Checks the files for changes of their modification time or size every interval
+// until the context is cancelled, and calls onChange after a change once the files
+// have stayed the same for an interval, so that files still being written are not
+// read. Changes made before WatchFiles is called are not reported.
+func WatchFiles(ctx context.Context, paths []string, interval time.Duration, onChange func()) error {
+	last, err := getFileStates(paths)
+	if err != nil {
+		return err
+	}
+	reported := last
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := getFileStates(paths)
+		if err != nil {
+			return err
+		}
+		if fileStatesEqual(current, last) && !fileStatesEqual(current, reported) {
+			onChange()
+			reported = current
+		}
+		last = current
+	}
+}
+
+// For holding how the results of a comparison changed since an earlier one.
+type ResultDelta struct {
+	RowCount1Before int
+	RowCount1After  int
+	RowCount2Before int
+	RowCount2After  int
+	Added1          [][]csvcheck.StringHashable // The rows in the later results of the first file but not the earlier ones.
+	Removed1        [][]csvcheck.StringHashable
+	Added2          [][]csvcheck.StringHashable
+	Removed2        [][]csvcheck.StringHashable
+}
+
+// Returns true iff the results did not change.
+func (d *ResultDelta) IsEmpty() bool {
+	return len(d.Added1) == 0 && len(d.Removed1) == 0 && len(d.Added2) == 0 && len(d.Removed2) == 0
+}
+
+// Returns the rows of res that are not in other. Rows are told apart by the names and
+// values of their columns other than the index and hash columns, as the indices of
+// unchanged rows shift when rows are added before them. Duplicate rows are counted.
+func getRowsNotIn(res, other [][]csvcheck.StringHashable) [][]csvcheck.StringHashable {
+	rowKey := func(header, row []csvcheck.StringHashable) string {
+		var buffer []byte
+		for _, i := range getComparedIndices(header, nil, nil) {
+			value := ""
+			if i < len(row) {
+				value = row[i].StringHash()
+			}
+			buffer = appendLengthPrefixed(buffer, header[i].StringHash(), value)
+		}
+		return string(buffer)
+	}
+
+	occurrences := make(map[string]int)
+	for _, row := range other[1:] {
+		occurrences[rowKey(other[0], row)]++
+	}
+	rows := [][]csvcheck.StringHashable{}
+	for _, row := range res[1:] {
+		key := rowKey(res[0], row)
+		if occurrences[key] > 0 {
+			occurrences[key]--
+		} else {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// Returns how the result rows of both files changed from the earlier result to the later one.
+func GetResultDelta(before, after *Result) *ResultDelta {
+	return &ResultDelta{
+		RowCount1Before: len(before.Rows1) - 1,
+		RowCount1After:  len(after.Rows1) - 1,
+		RowCount2Before: len(before.Rows2) - 1,
+		RowCount2After:  len(after.Rows2) - 1,
+		Added1:          getRowsNotIn(after.Rows1, before.Rows1),
+		Removed1:        getRowsNotIn(before.Rows1, after.Rows1),
+		Added2:          getRowsNotIn(after.Rows2, before.Rows2),
+		Removed2:        getRowsNotIn(before.Rows2, after.Rows2),
+	}
+}
+
+// Returns lines summarizing the delta for each file, followed by the first added
+// rows prefixed with + and removed rows prefixed with -.
+func FormatResultDelta(delta *ResultDelta, fileName1, fileName2 string) string {
+	if delta.IsEmpty() {
+		return "The results did not change.\n"
+	}
+
+	formatRows := func(prefix string, rows [][]csvcheck.StringHashable) string {
+		res := ""
+		for _, row := range rows[:min(len(rows), maxDeltaRows)] {
+			// A row is not a proper csv array on its own, as its values may repeat.
+			var line strings.Builder
+			writer := csv.NewWriter(&line)
+			writer.Write(getRowStrings(row))
+			writer.Flush()
+			res += fmt.Sprintf("  %s %s\n", prefix, strings.TrimSuffix(line.String(), "\n"))
+		}
+		if len(rows) > maxDeltaRows {
+			res += fmt.Sprintf("  ... and %s more\n", formatThousands(len(rows)-maxDeltaRows))
+		}
+		return res
+	}
+
+	res := ""
+	for _, file := range []struct {
+		name           string
+		before, after  int
+		added, removed [][]csvcheck.StringHashable
+	}{
+		{fileName1, delta.RowCount1Before, delta.RowCount1After, delta.Added1, delta.Removed1},
+		{fileName2, delta.RowCount2Before, delta.RowCount2After, delta.Added2, delta.Removed2},
+	} {
+		res += fmt.Sprintf("Results for file %s: %s rows, was %s (%s added, %s removed)\n",
+			file.name, formatThousands(file.after), formatThousands(file.before), formatThousands(len(file.added)), formatThousands(len(file.removed)))
+		res += formatRows("+", file.added)
+		res += formatRows("-", file.removed)
+	}
+	return res
+}
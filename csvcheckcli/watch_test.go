@@ -0,0 +1,97 @@
+package csvcheckcli_test
+
+import (
+	"context"
+	"csvcheckcli/csvcheckcli"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetResultDelta(t *testing.T) {
+	before := &csvcheckcli.Result{
+		Rows1: Get2DArrayFromCsvString(fmt.Sprintf("a,b,%s\n1,1,1\n2,2,2\n2,2,3\n", csvcheckcli.IndexColumnName)),
+		Rows2: Get2DArrayFromCsvString("a,b\n5,5\n"),
+	}
+	after := &csvcheckcli.Result{
+		Rows1: Get2DArrayFromCsvString(fmt.Sprintf("a,b,%s\n0,0,1\n1,1,2\n2,2,3\n", csvcheckcli.IndexColumnName)),
+		Rows2: Get2DArrayFromCsvString("a,b\n5,5\n"),
+	}
+
+	delta := csvcheckcli.GetResultDelta(before, after)
+
+	assert.False(t, delta.IsEmpty())
+	assert.Equal(t, 3, delta.RowCount1Before)
+	assert.Equal(t, 3, delta.RowCount1After)
+	assert.Equal(t, Get2DArrayFromCsvString("0,0,1\n"), delta.Added1)
+	assert.Equal(t, Get2DArrayFromCsvString("2,2,3\n"), delta.Removed1)
+	assert.Empty(t, delta.Added2)
+	assert.Empty(t, delta.Removed2)
+	assert.Equal(t, `Results for file x.csv: 3 rows, was 3 (1 added, 1 removed)
+  + 0,0,1
+  - 2,2,3
+Results for file y.csv: 1 rows, was 1 (0 added, 0 removed)
+`, csvcheckcli.FormatResultDelta(delta, "x.csv", "y.csv"))
+
+	delta = csvcheckcli.GetResultDelta(after, after)
+	assert.True(t, delta.IsEmpty())
+	assert.Equal(t, "The results did not change.\n", csvcheckcli.FormatResultDelta(delta, "x.csv", "y.csv"))
+}
+
+func TestFormatResultDeltaManyRows(t *testing.T) {
+	rows := "a\n"
+	for i := 0; i < 12; i++ {
+		rows += fmt.Sprintf("%d\n", i)
+	}
+	before := &csvcheckcli.Result{Rows1: Get2DArrayFromCsvString("a\n"), Rows2: Get2DArrayFromCsvString("a\n")}
+	after := &csvcheckcli.Result{Rows1: Get2DArrayFromCsvString(rows), Rows2: Get2DArrayFromCsvString("a\n")}
+
+	res := csvcheckcli.FormatResultDelta(csvcheckcli.GetResultDelta(before, after), "x.csv", "y.csv")
+
+	assert.Equal(t, 10, strings.Count(res, "  + "))
+	assert.Contains(t, res, "  ... and 2 more\n")
+}
+
+func TestWatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	path1 := filepath.Join(dir, "x.csv")
+	path2 := filepath.Join(dir, "y.csv")
+	assert.Nil(t, os.WriteFile(path1, []byte("a\n1\n"), 0644))
+	assert.Nil(t, os.WriteFile(path2, []byte("a\n1\n"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan struct{}, 10)
+	done := make(chan error)
+	go func() {
+		done <- csvcheckcli.WatchFiles(ctx, []string{path1, path2}, 10*time.Millisecond, func() {
+			changes <- struct{}{}
+		})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, changes)
+
+	assert.Nil(t, os.WriteFile(path2, []byte("a\n1\n2\n"), 0644))
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the change was not reported")
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, changes)
+
+	assert.Nil(t, os.Remove(path1))
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the removal was not reported")
+	}
+
+	cancel()
+	assert.Nil(t, <-done)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"csvcheckcli/csvcheckcli"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+	"github.com/spf13/pflag"
+)
+
+// Runs the fingerprint command with the arguments following the command name.
+func runFingerprint(args []string) {
+	cfg, err := csvcheckcli.ParseFingerprintArgs(args)
+	if errors.Is(err, pflag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("error parsing input:\n%s", err)
+	}
+
+	csvPath := filepath.Join(cfg.InputDir, cfg.File)
+	csvArray, err := readCsvPath(csvPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fingerprints, err := csvcheckcli.GetFingerprintArray(csvArray, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.Against != "" {
+		oldFingerprints, err := readCsvPath(cfg.Against)
+		if err != nil {
+			log.Fatal(err)
+		}
+		diff, err := csvcheckcli.CompareFingerprints(oldFingerprints, fingerprints)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printFingerprintDiff(diff)
+	}
+
+	if cfg.OutputDir != "" {
+		fileName := filepath.Base(csvPath)
+		fileNameNoExt := fileName[:len(fileName)-len(filepath.Ext(fileName))]
+		resFileName := fmt.Sprintf("csvcheck_fingerprint_%s.csv", fileNameNoExt)
+		if cfg.AddTimestamp {
+			resFileName = fmt.Sprintf("csvcheck_fingerprint_%s_%s.csv", fileNameNoExt, time.Now().Format("2006_01_02_15_04_05"))
+		}
+
+		outputPath := filepath.Join(cfg.OutputDir, resFileName)
+		resString, err := csvcheckcli.FormatCsvArray(fingerprints)
+		if err != nil {
+			log.Fatal(err)
+		}
+		writeOutputFiles("Fingerprints", []string{outputPath}, []string{resString}, cfg.WriteOptions(), cfg.NoClobber)
+	} else if cfg.Against == "" {
+		resString, err := csvcheckcli.FormatCsvArray(fingerprints)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(resString)
+	}
+}
+
+// Prints the new, removed and changed rows of the fingerprint diff.
+func printFingerprintDiff(diff csvcheckcli.FingerprintDiff) {
+	for _, section := range []struct {
+		name string
+		rows [][]csvcheck.StringHashable
+	}{
+		{"New", diff.New},
+		{"Removed", diff.Removed},
+		{"Changed", diff.Changed},
+	} {
+		resString, _ := csvcheck.PrettyFormatCsvArray(section.rows, 2, -1)
+		fmt.Printf("%s rows (%d):\n%s\n", section.name, len(section.rows)-1, resString)
+	}
+}
+
+// Reads all the records of the csv file at the path.
+func readCsvPath(csvPath string) ([][]csvcheck.StringHashable, error) {
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return csvcheckcli.ReadCsv(file)
+}
@@ -1,68 +1,289 @@
 package main
 
 import (
+	"context"
 	"csvcheckcli/csvcheckcli"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/BrianWeiHaoMa/csvcheck"
+	"github.com/spf13/pflag"
 )
 
 func main() {
-	input, err := csvcheckcli.ParseUserInput(nil)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fingerprint":
+			runFingerprint(os.Args[2:])
+			return
+		case "snapshot":
+			runSnapshot(os.Args[2:])
+			return
+		case "history":
+			runHistory(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		}
+	}
+
+	cfg, err := csvcheckcli.ParseArgs(os.Args[1:])
+	if errors.Is(err, pflag.ErrHelp) {
+		return
+	}
 	if err != nil {
 		log.Fatalf("error parsing input:\n%s", err)
 	}
 
-	csvPath1 := filepath.Join(*input.InputDir, (*input.Files)[0])
-	csvPath2 := filepath.Join(*input.InputDir, (*input.Files)[1])
-
-	csvArray1 := csvcheckcli.ReadCsvFile(csvPath1)
-	csvArray2 := csvcheckcli.ReadCsvFile(csvPath2)
+	csvPath1 := filepath.Join(cfg.InputDir, cfg.Files[0])
+	csvPath2 := filepath.Join(cfg.InputDir, cfg.Files[1])
 
 	fileName1 := filepath.Base(csvPath1)
 	fileName2 := filepath.Base(csvPath2)
 
 	currentTime := time.Now()
 	fmt.Printf("Start time: %s\n\n", currentTime.Format("2006-01-02 15:04:05"))
+	nameData := csvcheckcli.OutputNameData{
+		Function: cfg.Function,
+		Method:   cfg.Method,
+		File1:    csvPath1,
+		File2:    csvPath2,
+		InputDir: cfg.InputDir,
+		Time:     currentTime,
+		RunID:    csvcheckcli.NewRunID(),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		// Restore the default behaviour so that a second interrupt exits right away.
+		<-ctx.Done()
+		stop()
+	}()
+
+	var progressBar *csvcheckcli.ProgressBar
+	if cfg.Progress {
+		progressBar = csvcheckcli.NewProgressBar(os.Stderr)
+		cfg.ProgressReporter = progressBar
+	}
+
+	result, err := compareCsvFiles(ctx, csvPath1, csvPath2, cfg)
+	if progressBar != nil {
+		progressBar.Finish()
+	}
+	if err != nil && (result == nil || !result.Partial) {
+		log.Fatal(err)
+	}
+	if result.Partial {
+		fmt.Printf("Interrupted, the results only cover the rows read so far.\n\n")
+	}
+	if result.Stats != nil {
+		writeReport(result.Stats, csvcheckcli.FormatStatsSummary(result.Stats), csvcheckcli.GetStatsArray(result.Stats), nameData, cfg)
+		return
+	}
+	if result.SchemaDiff != nil {
+		writeReport(result.SchemaDiff, csvcheckcli.FormatSchemaDiffSummary(result.SchemaDiff), csvcheckcli.GetSchemaDiffArray(result.SchemaDiff), nameData, cfg)
+		if result.SchemaDiff.IsBreaking() {
+			stop()
+			os.Exit(1)
+		}
+		return
+	}
+	if result.Reconciliation != nil {
+		writeReport(result.Reconciliation, csvcheckcli.FormatReconciliationSummary(result.Reconciliation), csvcheckcli.GetReconciliationArray(result.Reconciliation), nameData, cfg)
+		if !result.Reconciliation.Matches() {
+			stop()
+			os.Exit(1)
+		}
+		return
+	}
+	res1, res2 := result.Rows1, result.Rows2
+	if cfg.Combine {
+		source1, source2 := fileName1, fileName2
+		if source1 == source2 {
+			source1, source2 = cfg.Files[0], cfg.Files[1]
+		}
+		writeCombinedResults(res1, res2, source1, source2, nameData, cfg)
+	} else {
+		writeResults(res1, res2, fileName1, fileName2, nameData, cfg)
+	}
+
+	if cfg.Watch {
+		cfg.ProgressReporter = nil
+		watchCsvFiles(ctx, csvPath1, csvPath2, fileName1, fileName2, result, cfg)
+	}
+}
+
+// Prints the results of each file, or browses them if tui is given, and writes them
+// to a file each in the output directory if one is given.
+func writeResults(res1, res2 [][]csvcheck.StringHashable, fileName1, fileName2 string, nameData csvcheckcli.OutputNameData, cfg csvcheckcli.Config) {
+	resString1, _ := csvcheckcli.FormatCsvArray(res1)
+	resString2, _ := csvcheckcli.FormatCsvArray(res2)
+
+	if cfg.TUI && csvcheckcli.IsTerminal(os.Stdin, os.Stdout) {
+		browser := csvcheckcli.NewBrowser(fileName1, fileName2, res1, res2, cfg.KeyColumns)
+		err := csvcheckcli.RunBrowser(browser, os.Stdin, os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		displayString1 := formatResultDisplay(res1, cfg)
+		displayString2 := formatResultDisplay(res2, cfg)
+		if cfg.PrintInMarkdownFormat {
+			fmt.Printf("%s\n", csvcheckcli.FormatMarkdownSummary(fileName1, fileName2, len(res1)-1, len(res2)-1, cfg))
+			fmt.Printf("Results for file %s:\n\n%s\n", fileName1, displayString1)
+			fmt.Printf("Results for file %s:\n\n%s\n", fileName2, displayString2)
+		} else {
+			fmt.Printf("Results for file %s:\n%s\n", fileName1, displayString1)
+			fmt.Printf("Results for file %s:\n%s\n", fileName2, displayString2)
+		}
+	}
+
+	if cfg.OutputDir != "" {
+		nameData.File = 1
+		outputPath1 := getOutputPath(cfg, nameData, false)
+		nameData.File = 2
+		outputPath2 := getOutputPath(cfg, nameData, false)
+		if outputPath1 == outputPath2 {
+			log.Fatalf("the output name gives the same path %s for the results of both files", outputPath1)
+		}
+
+		writeOutputFiles("Results", []string{outputPath1, outputPath2}, []string{resString1, resString2}, cfg.WriteOptions(), cfg.NoClobber)
+	}
+}
 
-	res1, res2, err := csvcheckcli.GetResArrays(csvArray1, csvArray2, input)
+// Prints the results of both files as a single table with the source of each row,
+// and writes it to a single file in the output directory if one is given.
+func writeCombinedResults(res1, res2 [][]csvcheck.StringHashable, source1, source2 string, nameData csvcheckcli.OutputNameData, cfg csvcheckcli.Config) {
+	combined, err := csvcheckcli.CombineResArrays(res1, res2, source1, source2)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	resString1, _ := csvcheck.StringFormatCsvArray(res1)
-	resString2, _ := csvcheck.StringFormatCsvArray(res2)
-	if *input.PrintInCsvFormat {
-		fmt.Printf("Results for file %s:\n%s\n", fileName1, resString1)
-		fmt.Printf("Results for file %s:\n%s\n", fileName2, resString2)
+	if cfg.PrintInMarkdownFormat {
+		fmt.Printf("%s\n", csvcheckcli.FormatMarkdownSummary(source1, source2, len(res1)-1, len(res2)-1, cfg))
+		fmt.Printf("Results:\n\n%s\n", formatResultDisplay(combined, cfg))
 	} else {
-		prettyResString1, _ := csvcheck.PrettyFormatCsvArray(res1, 2, *input.PrettyFormatMaxLength)
-		prettyResString2, _ := csvcheck.PrettyFormatCsvArray(res2, 2, *input.PrettyFormatMaxLength)
-		fmt.Printf("Results for file %s:\n%s\n", fileName1, prettyResString1)
-		fmt.Printf("Results for file %s:\n%s\n", fileName2, prettyResString2)
-	}
-
-	if *input.OutputDir != "" {
-		fileNameNoExt1 := fileName1[:len(fileName1)-len(filepath.Ext(fileName1))]
-		fileNameNoExt2 := fileName2[:len(fileName2)-len(filepath.Ext(fileName2))]
-
-		resFileName1 := fmt.Sprintf("csvcheck_%s.csv", fileNameNoExt1)
-		resFileName2 := fmt.Sprintf("csvcheck_%s.csv", fileNameNoExt2)
-		if *input.AddTimestamp {
-			timeString := currentTime.Format("2006_01_02_15_04_05")
-			resFileName1 = fmt.Sprintf("csvcheck_%s_%s.csv", fileNameNoExt1, timeString)
-			resFileName2 = fmt.Sprintf("csvcheck_%s_%s.csv", fileNameNoExt2, timeString)
+		fmt.Printf("Results:\n%s\n", formatResultDisplay(combined, cfg))
+	}
+
+	if cfg.OutputDir != "" {
+		nameData.File = 0
+		outputPath := getOutputPath(cfg, nameData, true)
+		resString, _ := csvcheckcli.FormatCsvArray(combined)
+		writeOutputFiles("Results", []string{outputPath}, []string{resString}, cfg.WriteOptions(), cfg.NoClobber)
+	}
+}
+
+// Returns the part of the result array to display formatted for printing, followed
+// by a note on the rows left out, based off of the config.
+func formatResultDisplay(res [][]csvcheck.StringHashable, cfg csvcheckcli.Config) string {
+	displayArray, omitted := csvcheckcli.GetDisplayArray(res, cfg)
+	displayString := formatDisplayArray(displayArray, cfg)
+	if omitted > 0 {
+		displayString += csvcheckcli.FormatOmittedRowsFooter(omitted)
+	}
+	return displayString
+}
+
+// Returns the path of an output file in the output directory named after the output
+// name template of the config. report is true for a single file for both input files.
+func getOutputPath(cfg csvcheckcli.Config, nameData csvcheckcli.OutputNameData, report bool) string {
+	outputName, err := csvcheckcli.FormatOutputName(cfg.OutputNameTemplate(report), nameData)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return filepath.Join(cfg.OutputDir, outputName)
+}
+
+// Compares the two csv files based off of the config.
+func compareCsvFiles(ctx context.Context, csvPath1, csvPath2 string, cfg csvcheckcli.Config) (*csvcheckcli.Result, error) {
+	file1, err := os.Open(csvPath1)
+	if err != nil {
+		return nil, err
+	}
+	defer file1.Close()
+
+	file2, err := os.Open(csvPath2)
+	if err != nil {
+		return nil, err
+	}
+	defer file2.Close()
+
+	return csvcheckcli.Compare(ctx, file1, file2, cfg)
+}
+
+// Returns the array formatted for printing based off of the config.
+func formatDisplayArray(arr [][]csvcheck.StringHashable, cfg csvcheckcli.Config) string {
+	var res string
+	switch {
+	case cfg.PrintInCsvFormat:
+		res, _ = csvcheckcli.FormatCsvArray(arr)
+	case cfg.PrintInJsonFormat:
+		res, _ = csvcheckcli.JsonFormatCsvArray(arr)
+	case cfg.PrintInMarkdownFormat:
+		res, _ = csvcheckcli.MarkdownFormatCsvArray(arr, cfg.PrettyFormatMaxLength)
+	default:
+		res, _ = csvcheck.PrettyFormatCsvArray(arr, 2, cfg.PrettyFormatMaxLength)
+	}
+	return res
+}
+
+// Prints a report of a function without result rows, as json or as its summary
+// followed by its array, and writes the array to the output directory if one is given.
+func writeReport(report any, summary string, reportArray [][]csvcheck.StringHashable, nameData csvcheckcli.OutputNameData, cfg csvcheckcli.Config) {
+	if cfg.PrintInJsonFormat {
+		reportJson, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatal(err)
 		}
+		fmt.Printf("%s\n", reportJson)
+	} else {
+		fmt.Printf("%s\n%s\n", summary, formatDisplayArray(reportArray, cfg))
+	}
 
-		outputPath1 := filepath.Join(*input.OutputDir, resFileName1)
-		outputPath2 := filepath.Join(*input.OutputDir, resFileName2)
+	if cfg.OutputDir != "" {
+		nameData.File = 0
+		outputPath := getOutputPath(cfg, nameData, true)
+		resString, _ := csvcheckcli.FormatCsvArray(reportArray)
+		writeOutputFiles("Results", []string{outputPath}, []string{resString}, cfg.WriteOptions(), cfg.NoClobber)
+	}
+}
+
+// Writes the contents to the paths based off of the write options and prints which
+// of them were written. Files that already exist are skipped if noClobber is true.
+func writeOutputFiles(what string, paths, contents []string, options csvcheckcli.WriteOptions, noClobber bool) {
+	if !options.Overwrite && !noClobber {
+		// Refuse before writing any of the files rather than leaving only some of them written.
+		for _, path := range paths {
+			if _, err := os.Lstat(path); err == nil {
+				log.Fatalf("%s already exists, use --overwrite to replace it or --no-clobber to skip it", path)
+			}
+		}
+	}
 
-		csvcheckcli.WriteString(outputPath1, resString1)
-		csvcheckcli.WriteString(outputPath2, resString2)
+	written := []string{}
+	for i, path := range paths {
+		err := csvcheckcli.WriteFile(path, contents[i], options)
+		if noClobber && errors.Is(err, csvcheckcli.ErrFileExists) {
+			fmt.Printf("Skipped %s as it already exists.\n", path)
+			continue
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		written = append(written, path)
+	}
 
-		fmt.Printf("Results written to %s and %s.\n", outputPath1, outputPath2)
+	if len(written) > 0 {
+		fmt.Printf("%s written to %s.\n", what, strings.Join(written, " and "))
 	}
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"csvcheckcli/csvcheckcli"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// The time given to requests in progress to finish when the server is interrupted.
+const shutdownTimeout = 30 * time.Second
+
+// Runs the serve command with the arguments following the command name.
+func runServe(args []string) {
+	cfg, err := csvcheckcli.ParseServeArgs(args)
+	if errors.Is(err, pflag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("error parsing input:\n%s", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	server := csvcheckcli.NewServer(cfg)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+	log.Printf("Listening on %s.", cfg.Addr)
+
+	select {
+	case err = <-serverErr:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+
+	stop()
+	log.Printf("Shutting down.")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err = server.Shutdown(shutdownCtx)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"csvcheckcli/csvcheckcli"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/BrianWeiHaoMa/csvcheck"
+	"github.com/spf13/pflag"
+)
+
+// Runs the snapshot command with the arguments following the command name.
+func runSnapshot(args []string) {
+	cfg, err := csvcheckcli.ParseSnapshotArgs(args)
+	if errors.Is(err, pflag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("error parsing input:\n%s", err)
+	}
+
+	currentTime := time.Now()
+	csvPath := filepath.Join(cfg.InputDir, cfg.File)
+	csvArray, err := readCsvPath(csvPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fingerprints, err := csvcheckcli.GetFingerprintArray(csvArray, cfg.FingerprintConfig())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	store := csvcheckcli.NewSnapshotStore(cfg.SnapshotDir)
+	entry := csvcheckcli.HistoryEntry{
+		Time: currentTime,
+		Name: cfg.DatasetName(),
+		File: csvPath,
+		Rows: len(fingerprints) - 1,
+	}
+
+	if cfg.AgainstBaseline != "" {
+		baselineID, baseline, err := store.Latest(cfg.AgainstBaseline)
+		if err != nil {
+			log.Fatal(err)
+		}
+		diff, err := csvcheckcli.CompareFingerprints(baseline, fingerprints)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("Compared against snapshot %s of %s.\n\n", baselineID, cfg.AgainstBaseline)
+		printFingerprintDiff(diff)
+		entry.Baseline = baselineID
+		entry.New = len(diff.New) - 1
+		entry.Removed = len(diff.Removed) - 1
+		entry.Changed = len(diff.Changed) - 1
+	}
+
+	if !cfg.NoSave {
+		entry.Snapshot, err = store.Save(entry.Name, fingerprints, currentTime)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Snapshot %s of %s saved.\n", entry.Snapshot, entry.Name)
+
+		pruned, err := store.Prune(entry.Name, cfg.Retain)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(pruned) > 0 {
+			fmt.Printf("Deleted %d old snapshots of %s.\n", len(pruned), entry.Name)
+		}
+	}
+
+	err = store.AddHistory(entry)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Runs the history command with the arguments following the command name.
+func runHistory(args []string) {
+	cfg, err := csvcheckcli.ParseHistoryArgs(args)
+	if errors.Is(err, pflag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("error parsing input:\n%s", err)
+	}
+
+	entries, err := csvcheckcli.NewSnapshotStore(cfg.SnapshotDir).History(cfg.Name)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	historyArray := csvcheckcli.GetHistoryArray(entries)
+	var historyString string
+	if cfg.PrintInCsvFormat {
+		historyString, _ = csvcheckcli.FormatCsvArray(historyArray)
+	} else {
+		historyString, _ = csvcheck.PrettyFormatCsvArray(historyArray, 2, -1)
+	}
+	fmt.Print(historyString)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"csvcheckcli/csvcheckcli"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Compares the csv files again whenever they change until the context is cancelled,
+// and prints how the results changed since the previous comparison.
+func watchCsvFiles(ctx context.Context, csvPath1, csvPath2, fileName1, fileName2 string, previous *csvcheckcli.Result, cfg csvcheckcli.Config) {
+	fmt.Printf("Watching %s and %s for changes, press Ctrl+C to stop.\n\n", csvPath1, csvPath2)
+	err := csvcheckcli.WatchFiles(ctx, []string{csvPath1, csvPath2}, cfg.WatchInterval, func() {
+		result, err := compareCsvFiles(ctx, csvPath1, csvPath2, cfg)
+		if ctx.Err() != nil {
+			return
+		}
+		fmt.Printf("Change detected at %s.\n", time.Now().Format("2006-01-02 15:04:05"))
+		if err != nil {
+			// The files may be fixed by the next change, so keep watching.
+			fmt.Printf("The comparison failed: %s\n\n", err)
+			return
+		}
+		delta := csvcheckcli.GetResultDelta(previous, result)
+		fmt.Printf("%s\n", csvcheckcli.FormatResultDelta(delta, fileName1, fileName2))
+		previous = result
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}